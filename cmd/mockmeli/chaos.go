@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Knobs son las fallas que simula el mock. Se configuran con flags al iniciar o con
+// PUT /_mock/config mientras corre.
+type Knobs struct {
+	Latency     time.Duration // demora de cada respuesta
+	Jitter      time.Duration // demora aleatoria adicional, entre 0 y Jitter
+	ErrorRate   float64       // proporcion de respuestas con error (0 a 1)
+	ErrorStatus int           // status de las respuestas con error
+	RateLimit   int           // solicitudes por segundo antes de responder 429 (0 = sin limite)
+	RetryAfter  time.Duration // Retry-After de las respuestas 429
+}
+
+// validate verifica que los knobs esten dentro de sus rangos.
+func (k Knobs) validate() error {
+	switch {
+	case k.Latency < 0 || k.Jitter < 0 || k.RetryAfter < 0:
+		return errors.New("las duraciones no pueden ser negativas")
+	case k.ErrorRate < 0 || k.ErrorRate > 1:
+		return errors.New("error_rate debe estar entre 0 y 1")
+	case k.ErrorStatus < 400 || k.ErrorStatus > 599:
+		return errors.New("error_status debe ser un status 4xx o 5xx")
+	case k.RateLimit < 0:
+		return errors.New("rate_limit no puede ser negativo")
+	}
+	return nil
+}
+
+// view es la representacion JSON de los knobs, con las duraciones como texto ("200ms").
+func (k Knobs) view() gin.H {
+	return gin.H{
+		"latency":      k.Latency.String(),
+		"jitter":       k.Jitter.String(),
+		"error_rate":   k.ErrorRate,
+		"error_status": k.ErrorStatus,
+		"rate_limit":   k.RateLimit,
+		"retry_after":  k.RetryAfter.String(),
+	}
+}
+
+// knobsRequest es el cuerpo de PUT /_mock/config, con el formato de view. Los campos omitidos
+// conservan su valor.
+type knobsRequest struct {
+	Latency     *string  `json:"latency"`
+	Jitter      *string  `json:"jitter"`
+	ErrorRate   *float64 `json:"error_rate"`
+	ErrorStatus *int     `json:"error_status"`
+	RateLimit   *int     `json:"rate_limit"`
+	RetryAfter  *string  `json:"retry_after"`
+}
+
+// chaos aplica los Knobs a cada solicitud.
+type chaos struct {
+	mu          sync.Mutex
+	knobs       Knobs
+	window      time.Time // segundo en curso del rate limit
+	windowCount int       // solicitudes en el segundo en curso
+	now         func() time.Time
+}
+
+// newChaos crea un chaos con los knobs indicados. ErrorStatus cero toma 503.
+func newChaos(knobs Knobs) (*chaos, error) {
+	if knobs.ErrorStatus == 0 {
+		knobs.ErrorStatus = http.StatusServiceUnavailable
+	}
+	if err := knobs.validate(); err != nil {
+		return nil, err
+	}
+	return &chaos{knobs: knobs, now: time.Now}, nil
+}
+
+// Middleware demora la respuesta y, segun los knobs, responde 429 o un error en lugar de continuar.
+func (ch *chaos) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		knobs, limited := ch.admit()
+
+		delay := knobs.Latency
+		if knobs.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(knobs.Jitter) + 1))
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+
+		switch {
+		case limited:
+			c.Header("Retry-After", strconv.Itoa(int((knobs.RetryAfter+time.Second-1)/time.Second)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": "too many requests", "error": "rate_limited", "status": http.StatusTooManyRequests})
+		case knobs.ErrorRate > 0 && rand.Float64() < knobs.ErrorRate:
+			c.AbortWithStatusJSON(knobs.ErrorStatus, gin.H{"message": "simulated error", "error": http.StatusText(knobs.ErrorStatus), "status": knobs.ErrorStatus})
+		default:
+			c.Next()
+		}
+	}
+}
+
+// admit retorna los knobs vigentes e indica si la solicitud supera el rate limit.
+func (ch *chaos) admit() (Knobs, bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.knobs.RateLimit <= 0 {
+		return ch.knobs, false
+	}
+	now := ch.now().Truncate(time.Second)
+	if !now.Equal(ch.window) {
+		ch.window, ch.windowCount = now, 0
+	}
+	ch.windowCount++
+	return ch.knobs, ch.windowCount > ch.knobs.RateLimit
+}
+
+// GetKnobs devuelve la configuracion vigente.
+func (ch *chaos) GetKnobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ch.mu.Lock()
+		knobs := ch.knobs
+		ch.mu.Unlock()
+		c.JSON(http.StatusOK, knobs.view())
+	}
+}
+
+// SetKnobs modifica la configuracion vigente. La combinacion con los knobs vigentes, la
+// validacion y el reemplazo se hacen con ch.mu tomado, para que dos solicitudes que modifican
+// campos distintos no pierdan el cambio de la otra.
+func (ch *chaos) SetKnobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req knobsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ch.mu.Lock()
+		knobs, err := req.merge(ch.knobs)
+		if err == nil {
+			ch.knobs = knobs
+		}
+		ch.mu.Unlock()
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, knobs.view())
+	}
+}
+
+// merge retorna knobs con los campos enviados en la solicitud, o un error si alguno es invalido.
+func (req knobsRequest) merge(knobs Knobs) (Knobs, error) {
+	durations := []struct {
+		value  *string
+		target *time.Duration
+	}{{req.Latency, &knobs.Latency}, {req.Jitter, &knobs.Jitter}, {req.RetryAfter, &knobs.RetryAfter}}
+	for _, d := range durations {
+		if d.value == nil {
+			continue
+		}
+		parsed, err := time.ParseDuration(*d.value)
+		if err != nil {
+			return Knobs{}, errors.New("duracion invalida: " + *d.value)
+		}
+		*d.target = parsed
+	}
+	if req.ErrorRate != nil {
+		knobs.ErrorRate = *req.ErrorRate
+	}
+	if req.ErrorStatus != nil {
+		knobs.ErrorStatus = *req.ErrorStatus
+	}
+	if req.RateLimit != nil {
+		knobs.RateLimit = *req.RateLimit
+	}
+	if err := knobs.validate(); err != nil {
+		return Knobs{}, err
+	}
+	return knobs, nil
+}
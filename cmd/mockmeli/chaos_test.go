@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRateLimitWindow verifica que se responde 429, con Retry-After, a partir de la solicitud
+// RateLimit+1 de cada segundo y que el limite se reinicia en el segundo siguiente.
+func TestRateLimitWindow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ch, err := newChaos(Knobs{RateLimit: 2, RetryAfter: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 12, 20, 13, 0, 0, 100, time.UTC)
+	ch.now = func() time.Time { return now }
+
+	router := gin.New()
+	router.GET("/currencies", ch.Middleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/currencies", nil))
+		return w
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if w := get(); w.Code != want {
+			t.Fatalf("solicitud %d: status %d, se esperaba %d", i+1, w.Code, want)
+		}
+	}
+	now = now.Add(800 * time.Millisecond) // mismo segundo
+	w := get()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("se esperaba 429 con Retry-After 2, se obtuvo %d y %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	now = now.Add(200 * time.Millisecond) // segundo siguiente
+	if w := get(); w.Code != http.StatusOK {
+		t.Fatalf("se esperaba reiniciar el limite en el segundo siguiente, status %d", w.Code)
+	}
+}
+
+// TestSetKnobs verifica que PUT /_mock/config modifica solo los campos enviados y rechaza los
+// valores fuera de rango sin aplicar ningun cambio.
+func TestSetKnobs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ch, err := newChaos(Knobs{RetryAfter: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.GET("/_mock/config", ch.GetKnobs())
+	router.PUT("/_mock/config", ch.SetKnobs())
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/_mock/config", bytes.NewBufferString(body)))
+		return w
+	}
+
+	if w := put(`{"latency":"200ms","rate_limit":5}`); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ch.knobs.Latency != 200*time.Millisecond || ch.knobs.RateLimit != 5 || ch.knobs.ErrorStatus != http.StatusServiceUnavailable || ch.knobs.RetryAfter != time.Second {
+		t.Fatalf("knobs inesperados: %+v", ch.knobs)
+	}
+
+	for _, body := range []string{
+		`{"rate_limit":-1}`,
+		`{"error_rate":1.5}`,
+		`{"error_status":200}`,
+		`{"latency":"-1s"}`,
+		`{"jitter":"abc"}`,
+		`{"latency":"1s","error_status":99}`,
+		`not json`,
+	} {
+		if w := put(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, se esperaba 400", body, w.Code)
+		}
+	}
+	if ch.knobs.Latency != 200*time.Millisecond || ch.knobs.RateLimit != 5 {
+		t.Fatalf("un PUT invalido modifico los knobs: %+v", ch.knobs)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_mock/config", nil))
+	var view map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil || view["latency"] != "200ms" || view["rate_limit"] != 5.0 {
+		t.Fatalf("GET /_mock/config = %s, %v", w.Body, err)
+	}
+}
+
+// TestSetKnobsConcurrent verifica que las solicitudes concurrentes que modifican campos distintos
+// no pierden los cambios de las demas: en cada ronda, cada solicitud modifica un campo una vez.
+func TestSetKnobsConcurrent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bodies := []string{`{"latency":"1s"}`, `{"jitter":"2s"}`, `{"retry_after":"3s"}`, `{"error_rate":0.5}`, `{"error_status":500}`, `{"rate_limit":7}`}
+	want := Knobs{Latency: time.Second, Jitter: 2 * time.Second, RetryAfter: 3 * time.Second, ErrorRate: 0.5, ErrorStatus: 500, RateLimit: 7}
+	for round := 0; round < 200; round++ {
+		ch, err := newChaos(Knobs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		router := gin.New()
+		router.PUT("/_mock/config", ch.SetKnobs())
+
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for _, body := range bodies {
+			wg.Add(1)
+			go func(body string) {
+				defer wg.Done()
+				<-start
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/_mock/config", bytes.NewBufferString(body)))
+				if w.Code != http.StatusOK {
+					t.Errorf("%s: status %d", body, w.Code)
+				}
+			}(body)
+		}
+		close(start)
+		wg.Wait()
+
+		if ch.knobs != want {
+			t.Fatalf("ronda %d: knobs %+v; se esperaba %+v", round, ch.knobs, want)
+		}
+	}
+}
+
+// TestNewChaosValidation verifica que los flags fuera de rango se rechazan al iniciar.
+func TestNewChaosValidation(t *testing.T) {
+	invalid := []Knobs{
+		{RateLimit: -1},
+		{ErrorStatus: 200},
+		{ErrorStatus: 600},
+		{ErrorRate: -0.1},
+		{Latency: -time.Second},
+	}
+	for _, knobs := range invalid {
+		if _, err := newChaos(knobs); err == nil {
+			t.Errorf("newChaos(%+v): se esperaba un error", knobs)
+		}
+	}
+	if ch, err := newChaos(Knobs{}); err != nil || ch.knobs.ErrorStatus != http.StatusServiceUnavailable {
+		t.Fatalf("newChaos(Knobs{}) = %+v, %v", ch, err)
+	}
+}
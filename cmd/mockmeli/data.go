@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/fixtures"
+	"github.com/gin-gonic/gin"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	// rateValidity es la vigencia de las cotizaciones que devuelve el mock (valid_until).
+	rateValidity = 10 * time.Minute
+	// dateLayout es el formato de las fechas de las cotizaciones de MELI.
+	dateLayout = "2006-01-02T15:04:05.000-0700"
+)
+
+// Archivos de los fixtures de los que se toman los datos (ver fixtureName en pkg/api).
+const (
+	baseCurrency      = "USD"
+	countriesFixture  = "classified_locations_countries.json"
+	countryFixture    = "classified_locations_countries_%s.json"
+	currenciesFixture = "currencies.json"
+	// ratesFixtures son las cotizaciones a USD, una por moneda.
+	ratesFixtures = "currency_conversions_search@from=*&to=" + baseCurrency + ".json"
+)
+
+// fixture son los campos de un fixture grabado de la API de MELI que usa el mock.
+type fixture struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// countrySummary son los campos de un país en la lista de países.
+type countrySummary struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Locale     string `json:"locale"`
+	CurrencyID string `json:"currency_id"`
+}
+
+// snapshot son los datos cargados de una version de los fixtures.
+type snapshot struct {
+	summaries  []countrySummary
+	countries  map[string]json.RawMessage // detalle de cada país por id
+	currencies []json.RawMessage
+	// usdRates es el valor en USD de una unidad de cada moneda, tomado de su cotizacion a USD; las
+	// cotizaciones entre dos monedas se calculan a partir de estos valores.
+	usdRates map[string]float64
+}
+
+// dataStore sirve los datos de los fixtures de la API de MELI. Los fixtures de un directorio se
+// recargan cuando se modifican; los embebidos (fixtures/meli) no cambian.
+type dataStore struct {
+	fsys fs.FS
+	dir  string // directorio de los fixtures; vacio si son los embebidos
+
+	mu      sync.Mutex
+	version string // firma de los archivos cargados, ver dirVersion
+	loaded  *snapshot
+}
+
+// newDataStore carga los fixtures de dir, o los embebidos de fixtures/meli si dir es vacio.
+func newDataStore(dir string) (*dataStore, error) {
+	store := &dataStore{dir: dir}
+	if dir == "" {
+		meli, err := fs.Sub(fixtures.Meli, "meli")
+		if err != nil {
+			return nil, err
+		}
+		store.fsys = meli
+	} else {
+		store.fsys = os.DirFS(dir)
+		version, err := dirVersion(dir)
+		if err != nil {
+			return nil, err
+		}
+		store.version = version
+	}
+
+	loaded, err := load(store.fsys)
+	if err != nil {
+		return nil, err
+	}
+	store.loaded = loaded
+	return store, nil
+}
+
+// current retorna los datos vigentes, recargando los fixtures del directorio si cambiaron. Si los
+// fixtures modificados no son validos se siguen usando los datos anteriores.
+func (s *dataStore) current() *snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir == "" {
+		return s.loaded
+	}
+	version, err := dirVersion(s.dir)
+	if err != nil || version == s.version {
+		return s.loaded
+	}
+	s.version = version
+
+	loaded, err := load(s.fsys)
+	if err != nil {
+		log.Printf("[WARN] No se pudieron recargar los fixtures de %s, se mantienen los datos anteriores: %v", s.dir, err)
+		return s.loaded
+	}
+	log.Printf("Fixtures recargados de %s", s.dir)
+	s.loaded = loaded
+	return loaded
+}
+
+// dirVersion resume los archivos JSON de dir (cantidad, tamaño y ultima modificacion) para
+// detectar cambios.
+func dirVersion(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var count int
+	var size int64
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		count++
+		size += info.Size()
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return fmt.Sprintf("%d/%d/%d", count, size, latest.UnixNano()), nil
+}
+
+// load lee y valida los fixtures de fsys: la lista de países, el detalle de cada uno, la lista de
+// monedas y las cotizaciones a USD.
+func load(fsys fs.FS) (*snapshot, error) {
+	loaded := &snapshot{
+		countries: make(map[string]json.RawMessage),
+		usdRates:  map[string]float64{baseCurrency: 1},
+	}
+	if err := readFixture(fsys, countriesFixture, &loaded.summaries); err != nil {
+		return nil, err
+	}
+	for _, summary := range loaded.summaries {
+		var country json.RawMessage
+		if err := readFixture(fsys, fmt.Sprintf(countryFixture, summary.ID), &country); err != nil {
+			return nil, err
+		}
+		loaded.countries[summary.ID] = country
+	}
+	if err := readFixture(fsys, currenciesFixture, &loaded.currencies); err != nil {
+		return nil, err
+	}
+
+	names, err := fs.Glob(fsys, ratesFixtures)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		var rate struct {
+			CurrencyBase string  `json:"currency_base"`
+			Rate         float64 `json:"rate"`
+		}
+		if err = readFixture(fsys, name, &rate); err != nil {
+			return nil, err
+		}
+		if rate.CurrencyBase == "" || rate.Rate <= 0 {
+			return nil, fmt.Errorf("%s: se requiere currency_base y un rate positivo", name)
+		}
+		loaded.usdRates[rate.CurrencyBase] = rate.Rate
+	}
+	return loaded, nil
+}
+
+// readFixture decodifica en out el cuerpo del fixture name, que debe ser una respuesta 200.
+func readFixture(fsys fs.FS, name string, out interface{}) error {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	var record fixture
+	if err = json.Unmarshal(content, &record); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if record.Status != http.StatusOK {
+		return fmt.Errorf("%s: se esperaba un fixture con status 200, tiene %d", name, record.Status)
+	}
+	if err = json.Unmarshal(record.Body, out); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// GetCountries responde /classified_locations/countries.
+func (s *dataStore) GetCountries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.current().summaries)
+	}
+}
+
+// GetCountry responde /classified_locations/countries/:id.
+func (s *dataStore) GetCountry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		country, ok := s.current().countries[c.Param("id")]
+		if !ok {
+			notFound(c, fmt.Sprintf("Country not found: %s", c.Param("id")))
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", country)
+	}
+}
+
+// GetCurrencies responde /currencies.
+func (s *dataStore) GetCurrencies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.current().currencies)
+	}
+}
+
+// GetCurrencyConversion responde /currency_conversions/search?from=&to=.
+func (s *dataStore) GetCurrencyConversion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to := c.Query("from"), c.Query("to")
+		rates := s.current().usdRates
+		fromUSD, toUSD := rates[from], rates[to]
+		if fromUSD <= 0 || toUSD <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid currency conversion: %s to %s", from, to), "error": "bad_request", "status": http.StatusBadRequest})
+			return
+		}
+
+		now := time.Now().UTC()
+		rate := fromUSD / toUSD
+		c.JSON(http.StatusOK, gin.H{
+			"currency_base":     from,
+			"currency_quote":    to,
+			"ratio":             rate,
+			"rate":              rate,
+			"inv_rate":          math.Round(1/rate*10000) / 10000,
+			"creation_date":     now.Format(dateLayout),
+			"valid_until":       now.Add(rateValidity).Format(dateLayout),
+			"last_updated_date": now.Format(dateLayout),
+		})
+	}
+}
+
+// GetToken responde /oauth/token con un token ficticio, para probar el flujo OAuth del servidor.
+func GetToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now().Unix()
+		c.JSON(http.StatusOK, gin.H{
+			"access_token":  fmt.Sprintf("APP_USR-mock-%d", now),
+			"token_type":    "Bearer",
+			"expires_in":    21600,
+			"scope":         "offline_access read",
+			"refresh_token": fmt.Sprintf("TG-mock-%d", now),
+		})
+	}
+}
+
+// notFound responde 404 con el formato de error de MELI.
+func notFound(c *gin.Context, message string) {
+	c.JSON(http.StatusNotFound, gin.H{"message": message, "error": "not_found", "status": http.StatusNotFound})
+}
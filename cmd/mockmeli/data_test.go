@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// get realiza un GET a router y decodifica la respuesta JSON en out.
+func get(t *testing.T, router http.Handler, target string, out interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if out != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+	}
+	return w.Code
+}
+
+// newTestRouter crea el router del mock sin fallas simuladas sobre los fixtures de dir.
+func newTestRouter(t *testing.T, dir string) (*gin.Engine, *dataStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	data, err := newDataStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := newChaos(Knobs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newRouter(data, ch), data
+}
+
+// TestEmbeddedFixtures verifica que sin -data se sirven los fixtures embebidos de fixtures/meli.
+func TestEmbeddedFixtures(t *testing.T) {
+	router, _ := newTestRouter(t, "")
+
+	var countries []countrySummary
+	if status := get(t, router, "/classified_locations/countries", &countries); status != http.StatusOK || len(countries) == 0 {
+		t.Fatalf("status %d, %d países", status, len(countries))
+	}
+	for _, summary := range countries {
+		var country countrySummary
+		if status := get(t, router, "/classified_locations/countries/"+summary.ID, &country); status != http.StatusOK || country != summary {
+			t.Errorf("%s: status %d, detalle %+v", summary.ID, status, country)
+		}
+		if status := get(t, router, "/currency_conversions/search?from="+summary.CurrencyID+"&to=USD", nil); status != http.StatusOK {
+			t.Errorf("%s: cotizacion de %s, status %d", summary.ID, summary.CurrencyID, status)
+		}
+	}
+	if status := get(t, router, "/classified_locations/countries/XX", nil); status != http.StatusNotFound {
+		t.Errorf("se esperaba 404 para un país inexistente, status %d", status)
+	}
+}
+
+// TestCurrencyConversion verifica el calculo de las cotizaciones entre dos monedas a partir de su
+// valor en USD.
+func TestCurrencyConversion(t *testing.T) {
+	router, data := newTestRouter(t, "")
+	rates := data.current().usdRates
+
+	tests := []struct{ from, to string }{{"ARS", "USD"}, {"USD", "BRL"}, {"BRL", "ARS"}, {"EUR", "MXN"}, {"USD", "USD"}}
+	for _, tt := range tests {
+		var conversion struct {
+			CurrencyBase  string  `json:"currency_base"`
+			CurrencyQuote string  `json:"currency_quote"`
+			Rate          float64 `json:"rate"`
+			InvRate       float64 `json:"inv_rate"`
+			CreationDate  string  `json:"creation_date"`
+			ValidUntil    string  `json:"valid_until"`
+		}
+		if status := get(t, router, "/currency_conversions/search?from="+tt.from+"&to="+tt.to, &conversion); status != http.StatusOK {
+			t.Fatalf("%s->%s: status %d", tt.from, tt.to, status)
+		}
+		want := rates[tt.from] / rates[tt.to]
+		if conversion.CurrencyBase != tt.from || conversion.CurrencyQuote != tt.to || math.Abs(conversion.Rate-want) > want*1e-9 {
+			t.Errorf("%s->%s: %+v, se esperaba rate %v", tt.from, tt.to, conversion, want)
+		}
+		if math.Abs(conversion.InvRate-1/want) > 0.0001 {
+			t.Errorf("%s->%s: inv_rate %v, se esperaba %v", tt.from, tt.to, conversion.InvRate, 1/want)
+		}
+		created, err1 := time.Parse(dateLayout, conversion.CreationDate)
+		validUntil, err2 := time.Parse(dateLayout, conversion.ValidUntil)
+		if err1 != nil || err2 != nil || validUntil.Sub(created) != rateValidity {
+			t.Errorf("%s->%s: vigencia inesperada %s - %s", tt.from, tt.to, conversion.CreationDate, conversion.ValidUntil)
+		}
+	}
+	if status := get(t, router, "/currency_conversions/search?from=XXX&to=USD", nil); status != http.StatusBadRequest {
+		t.Errorf("se esperaba 400 para una moneda desconocida, status %d", status)
+	}
+}
+
+// TestHotReload verifica que los fixtures de un directorio se recargan al modificarse y que un
+// fixture invalido no reemplaza los datos anteriores.
+func TestHotReload(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join("..", "..", "fixtures", "meli")
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(source, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, entry.Name()), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router, _ := newTestRouter(t, dir)
+	countryName := func() string {
+		var country countrySummary
+		get(t, router, "/classified_locations/countries/AR", &country)
+		return country.Name
+	}
+	if name := countryName(); name != "Argentina" {
+		t.Fatalf("país inesperado: %q", name)
+	}
+
+	file := filepath.Join(dir, "classified_locations_countries_AR.json")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"status":200,"body":{"id":"AR","name":"Argentina (editado)","locale":"es_AR","currency_id":"ARS"}}`, time.Now().Add(time.Minute))
+	if name := countryName(); name != "Argentina (editado)" {
+		t.Fatalf("se esperaba el fixture recargado, se obtuvo %q", name)
+	}
+
+	write(`{"status":200,"body":`, time.Now().Add(2*time.Minute))
+	if name := countryName(); name != "Argentina (editado)" {
+		t.Fatalf("un fixture invalido no deberia reemplazar los datos, se obtuvo %q", name)
+	}
+}
@@ -0,0 +1,68 @@
+// mockmeli es una API de MELI local para pruebas de punta a punta: sirve países, monedas y
+// cotizaciones desde los fixtures de fixtures/meli (embebidos en el binario, o de un directorio
+// editable con -data) y permite simular latencia, errores y rate limiting.
+//
+//	go run ./cmd/mockmeli -latency 200ms -error-rate 0.3
+//	API_URL=http://localhost:8082 go run ./cmd/server
+package main
+
+import (
+	"flag"
+	"github.com/gin-gonic/gin"
+	"log"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8082", "direccion en la que escucha el servidor")
+	dataDir := flag.String("data", "", "directorio de fixtures con el formato de fixtures/meli; se recarga al modificarse (vacio = fixtures/meli embebidos)")
+	var knobs Knobs
+	flag.DurationVar(&knobs.Latency, "latency", 0, "demora de cada respuesta")
+	flag.DurationVar(&knobs.Jitter, "jitter", 0, "demora aleatoria adicional, entre 0 y jitter")
+	flag.Float64Var(&knobs.ErrorRate, "error-rate", 0, "proporcion de respuestas con error (0 a 1)")
+	flag.IntVar(&knobs.ErrorStatus, "error-status", 503, "status de las respuestas con error")
+	flag.IntVar(&knobs.RateLimit, "rate-limit", 0, "solicitudes por segundo antes de responder 429 (0 = sin limite)")
+	flag.DurationVar(&knobs.RetryAfter, "retry-after", time.Second, "Retry-After de las respuestas 429")
+	flag.Parse()
+
+	chaos, err := newChaos(knobs)
+	if err != nil {
+		log.Fatalf("Configuracion invalida: %v", err)
+	}
+	data, err := newDataStore(*dataDir)
+	if err != nil {
+		log.Fatalf("Error al cargar los fixtures: %v", err)
+	}
+	source := *dataDir
+	if source == "" {
+		source = "fixtures/meli embebidos"
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := newRouter(data, chaos)
+
+	log.Printf("Mock de la API de MELI escuchando en %s (datos: %s)", *addr, source)
+	if err := router.Run(*addr); err != nil {
+		log.Fatalf("Error al iniciar el servidor: %v", err)
+	}
+}
+
+// newRouter registra los endpoints de MELI, afectados por chaos, y los de configuracion del mock.
+func newRouter(data *dataStore, chaos *chaos) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Logger(), gin.Recovery())
+
+	// Configuracion del mock en tiempo de ejecucion, sin latencia ni errores simulados
+	router.GET("/_mock/config", chaos.GetKnobs())
+	router.PUT("/_mock/config", chaos.SetKnobs())
+
+	meli := router.Group("", chaos.Middleware())
+	{
+		meli.GET("/classified_locations/countries", data.GetCountries())
+		meli.GET("/classified_locations/countries/:id", data.GetCountry())
+		meli.GET("/currencies", data.GetCurrencies())
+		meli.GET("/currency_conversions/search", data.GetCurrencyConversion())
+		meli.POST("/oauth/token", GetToken())
+	}
+	return router
+}
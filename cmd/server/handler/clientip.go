@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIPResolver determina la IP del cliente original. Solo se confia en el header configurado
+// (X-Forwarded-For o Forwarded) cuando la conexion proviene de un proxy de confianza. El otro header
+// se ignora: el proxy lo deja pasar sin modificarlo, por lo que lo puede escribir el cliente.
+type clientIPResolver struct {
+	trusted []*net.IPNet
+	header  string // config.HeaderXForwardedFor (por defecto) o config.HeaderForwarded
+}
+
+// resolve retorna la IP del cliente para la solicitud r.
+func (res *clientIPResolver) resolve(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote := net.ParseIP(host)
+	if remote == nil || !res.isTrusted(remote) {
+		return remote
+	}
+
+	// Se recorre la cadena de derecha a izquierda hasta encontrar el primer salto que no sea un proxy de confianza
+	hops := res.forwardedFor(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// Valor ofuscado o invalido ("unknown", "_hidden"): no se puede seguir la cadena
+			return remote
+		}
+		if !res.isTrusted(ip) || i == 0 {
+			return ip
+		}
+	}
+	return remote
+}
+
+// isTrusted indica si la IP pertenece a algun proxy de confianza.
+func (res *clientIPResolver) isTrusted(ip net.IP) bool {
+	for _, ipNet := range res.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor retorna los saltos declarados por los proxies en el header configurado, del
+// cliente original al ultimo proxy.
+func (res *clientIPResolver) forwardedFor(header http.Header) []string {
+	var hops []string
+	if res.header == config.HeaderForwarded {
+		for _, element := range strings.Split(strings.Join(header.Values(config.HeaderForwarded), ","), ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, stripPort(strings.Trim(value, `"`)))
+				}
+			}
+		}
+		return hops
+	}
+
+	for _, value := range header.Values(config.HeaderXForwardedFor) {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, stripPort(hop))
+			}
+		}
+	}
+	return hops
+}
+
+// stripPort quita el puerto y los corchetes de una direccion ("[2001:db8::1]:4711", "192.0.2.1:80").
+func stripPort(value string) string {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+}
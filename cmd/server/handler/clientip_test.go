@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestResolveClientIP verifica que solo se confia en el header configurado cuando la conexion
+// viene de un proxy de confianza, recorriendo la cadena de derecha a izquierda.
+func TestResolveClientIP(t *testing.T) {
+	trusted := []*net.IPNet{}
+	for _, cidr := range []string{"10.0.0.0/8", "2001:db8:ffff::/48"} {
+		ipNet, _ := config.ParseCIDR(cidr)
+		trusted = append(trusted, ipNet)
+	}
+	xff := &clientIPResolver{trusted: trusted, header: config.HeaderXForwardedFor}
+	forwarded := &clientIPResolver{trusted: trusted, header: config.HeaderForwarded}
+
+	tests := []struct {
+		name     string
+		resolver *clientIPResolver
+		remote   string
+		header   string
+		value    string
+		want     string
+	}{
+		{"sin proxy", xff, "203.0.113.7:5123", "", "", "203.0.113.7"},
+		{"par no confiable", xff, "203.0.113.7:5123", "X-Forwarded-For", "8.8.8.8", "203.0.113.7"},
+		{"un proxy", xff, "10.0.0.1:80", "X-Forwarded-For", "198.51.100.4", "198.51.100.4"},
+		{"cadena de proxies", xff, "10.0.0.1:80", "X-Forwarded-For", "198.51.100.4, 10.1.1.1, 10.2.2.2", "198.51.100.4"},
+		{"salto izquierdo falsificado", xff, "10.0.0.1:80", "X-Forwarded-For", "8.8.8.8, 198.51.100.4, 10.2.2.2", "198.51.100.4"},
+		{"solo proxies", xff, "10.0.0.1:80", "X-Forwarded-For", "10.1.1.1, 10.2.2.2", "10.1.1.1"},
+		{"salto ofuscado", xff, "10.0.0.1:80", "X-Forwarded-For", "198.51.100.4, unknown", "10.0.0.1"},
+		{"IPv6 con puerto", xff, "[2001:db8:ffff::1]:443", "X-Forwarded-For", "[2001:db8::42]:4711", "2001:db8::42"},
+		{"header vacio", xff, "10.0.0.1:80", "", "", "10.0.0.1"},
+		// Con X-Forwarded-For configurado, un Forwarded enviado por el cliente se ignora
+		{"Forwarded falsificado", xff, "10.0.0.1:80", "Forwarded", "for=8.8.8.8", "10.0.0.1"},
+		{"Forwarded", forwarded, "10.0.0.1:80", "Forwarded", `for=198.51.100.4;proto=https, for="[2001:db8:ffff::2]:80"`, "198.51.100.4"},
+		{"Forwarded ofuscado", forwarded, "10.0.0.1:80", "Forwarded", "for=_hidden", "10.0.0.1"},
+		{"Forwarded IPv6 con puerto", forwarded, "[2001:db8:ffff::1]:443", "Forwarded", `for="[2001:db8::42]:4711"`, "2001:db8::42"},
+		// Con Forwarded configurado, un X-Forwarded-For enviado por el cliente se ignora
+		{"X-Forwarded-For falsificado", forwarded, "10.0.0.1:80", "X-Forwarded-For", "8.8.8.8", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/ip/me", nil)
+		r.RemoteAddr = tt.remote
+		if tt.header != "" {
+			r.Header.Set(tt.header, tt.value)
+		}
+		if got := tt.resolver.resolve(r); got.String() != tt.want {
+			t.Errorf("%s: resolve() = %v, se esperaba %s", tt.name, got, tt.want)
+		}
+	}
+}
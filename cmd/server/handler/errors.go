@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"github.com/AleHts29/meli-challenge/pkg/store"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Codigos de error estables que se devuelven en el campo "code". A diferencia del
+// mensaje, no cambian entre versiones y los clientes pueden usarlos para decidir.
+const (
+	CodeInvalidRequest       = "INVALID_REQUEST"
+	CodeInvalidIP            = "INVALID_IP"
+	CodeInvalidAmount        = "INVALID_AMOUNT"
+	CodeIPBlocked            = "IP_BLOCKED"
+	CodeIPNotBlocked         = "IP_NOT_BLOCKED"
+	CodeBatchTooLarge        = "BATCH_TOO_LARGE"
+	CodeUnsupportedFormat    = "UNSUPPORTED_FORMAT"
+	CodeClientIPUnknown      = "CLIENT_IP_UNKNOWN"
+	CodeCountryNotOperated   = "COUNTRY_NOT_OPERATED"
+	CodeCountryNotFound      = "COUNTRY_NOT_FOUND"
+	CodeLocationNotFound     = "LOCATION_NOT_FOUND"
+	CodeSpecialPurposeIP     = "SPECIAL_PURPOSE_ADDRESS"
+	CodeUnknownCurrency      = "UNKNOWN_CURRENCY"
+	CodeGeolocationNotFound  = "GEOLOCATION_NOT_FOUND"
+	CodeGeolocationFailed    = "GEOLOCATION_FAILED"
+	CodeUpstreamUnavailable  = "UPSTREAM_UNAVAILABLE"
+	CodeUpstreamTimeout      = "UPSTREAM_TIMEOUT"
+	CodeUpstreamRateLimited  = "UPSTREAM_RATE_LIMITED"
+	CodeUpstreamBadResponse  = "UPSTREAM_BAD_RESPONSE"
+	CodeUpstreamNotFound     = "UPSTREAM_NOT_FOUND"
+	CodeUpstreamUnauthorized = "UPSTREAM_UNAUTHORIZED"
+	CodePersistenceFailed    = "PERSISTENCE_FAILED"
+	CodeInternalError        = "INTERNAL_ERROR"
+)
+
+// errorCodes son todos los codigos de error que puede devolver la API.
+var errorCodes = []string{
+	CodeInvalidRequest, CodeInvalidIP, CodeInvalidAmount, CodeIPBlocked, CodeIPNotBlocked,
+	CodeBatchTooLarge, CodeUnsupportedFormat, CodeClientIPUnknown, CodeCountryNotOperated, CodeCountryNotFound,
+	CodeLocationNotFound, CodeSpecialPurposeIP, CodeUnknownCurrency, CodeGeolocationNotFound,
+	CodeGeolocationFailed, CodeUpstreamUnavailable, CodeUpstreamTimeout, CodeUpstreamRateLimited,
+	CodeUpstreamBadResponse, CodeUpstreamNotFound, CodeUpstreamUnauthorized, CodePersistenceFailed, CodeInternalError,
+}
+
+// ErrorResponse es el cuerpo de todas las respuestas de error. Solo error y code estan siempre presentes.
+type ErrorResponse struct {
+	Error          string                        `json:"error"`
+	Code           string                        `json:"code"`
+	Details        string                        `json:"details,omitempty"` // formatos soportados, en los 406
+	Debug          string                        `json:"debug,omitempty"`   // texto original del error, sin traducir
+	IP             string                        `json:"ip,omitempty"`
+	Max            int                           `json:"max,omitempty"`
+	SpecialPurpose *models.SpecialPurposeAddress `json:"special_purpose,omitempty"`
+}
+
+// errorMapping asocia un error sentinela con su status HTTP y su codigo.
+type errorMapping struct {
+	target error
+	status int
+	code   string
+}
+
+// errorMappings se evalua en orden; el primer error que coincide con errors.Is define la respuesta.
+var errorMappings = []errorMapping{
+	{ipinfo.ErrSpecialPurposeAddress, http.StatusUnprocessableEntity, CodeSpecialPurposeIP},
+	{ipinfo.ErrCountryNotOperated, http.StatusUnprocessableEntity, CodeCountryNotOperated},
+	{ipinfo.ErrCountryNotFound, http.StatusNotFound, CodeCountryNotFound},
+	{ipinfo.ErrLocationNotFound, http.StatusNotFound, CodeLocationNotFound},
+	{ipinfo.ErrUnknownCurrency, http.StatusBadRequest, CodeUnknownCurrency},
+	{store.ErrIPNotFound, http.StatusBadGateway, CodeGeolocationNotFound},
+	{store.ErrLookupFailed, http.StatusBadGateway, CodeGeolocationFailed},
+	{api.ErrTimeout, http.StatusGatewayTimeout, CodeUpstreamTimeout},
+	{api.ErrRateLimited, http.StatusBadGateway, CodeUpstreamRateLimited},
+	{api.ErrNotFound, http.StatusBadGateway, CodeUpstreamNotFound},
+	{api.ErrUnauthorized, http.StatusBadGateway, CodeUpstreamUnauthorized},
+	{api.ErrBadResponse, http.StatusBadGateway, CodeUpstreamBadResponse},
+	{api.ErrUnavailable, http.StatusBadGateway, CodeUpstreamUnavailable},
+	// el plazo de la solicitud (REQUEST_TIMEOUT) vencio antes de consultar las APIs externas
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeUpstreamTimeout},
+}
+
+// classifyError retorna el status HTTP y el codigo correspondientes a un error del servicio.
+func classifyError(err error) (int, string) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.target) {
+			return mapping.status, mapping.code
+		}
+	}
+	return http.StatusInternalServerError, CodeInternalError
+}
+
+// withErrorCode completa el codigo de error de un resultado de lote.
+func withErrorCode(result models.LookupResult) models.LookupResult {
+	if result.Err != nil && result.Code == "" {
+		_, result.Code = classifyError(result.Err)
+	}
+	return result
+}
+
+// localizeResult reemplaza el error de un resultado de lote por el mensaje de su codigo, y los
+// avisos de sus datos por los de su fuente, en el idioma de la solicitud o del país de la IP.
+func localizeResult(c *gin.Context, result models.LookupResult) models.LookupResult {
+	var locale string
+	if result.Data != nil {
+		locale = result.Data.Locale
+	}
+	lang := requestLanguage(c, locale)
+	if result.Data != nil {
+		result.Data = localizeWarnings(lang, result.Data)
+	}
+	if result.Code != "" {
+		result.Error = translate(lang, result.Code)
+	}
+	return result
+}
+
+// localizeWarnings retorna una copia de info con cada aviso traducido a lang segun su fuente (o
+// segun su codigo si la fuente no esta en el catalogo), con su codigo de error y con el texto
+// original en Debug. Aplicarla de nuevo sobre el resultado no cambia los avisos ya traducidos.
+func localizeWarnings(lang string, info *models.CountryInfo) *models.CountryInfo {
+	if len(info.Warnings) == 0 {
+		return info
+	}
+	localized := *info
+	localized.Warnings = make([]models.Warning, len(info.Warnings))
+	for i, warning := range info.Warnings {
+		if warning.Err != nil {
+			_, warning.Code = classifyError(warning.Err)
+			warning.Debug = warning.Err.Error()
+			key := warningPrefix + warning.Source
+			if _, ok := messages[key]; !ok {
+				key = warning.Code
+			}
+			warning.Error = translate(lang, key)
+		}
+		localized.Warnings[i] = warning
+	}
+	return &localized
+}
+
+// respondServiceError escribe la respuesta de error correspondiente a un error del servicio. El
+// mensaje se toma del catalogo segun el codigo y el error original, sin traducir, se informa en debug.
+func respondServiceError(c *gin.Context, err error) {
+	status, code := classifyError(err)
+
+	extra := gin.H{"debug": err.Error()}
+	var specialPurpose *ipinfo.SpecialPurposeError
+	if errors.As(err, &specialPurpose) {
+		extra["special_purpose"] = specialPurpose.Address
+	}
+	respondError(c, status, code, message(c, code), extra)
+}
+
+// respondError escribe una respuesta de error con mensaje, codigo y campos adicionales.
+// El mensaje ya debe estar en el idioma de la solicitud (ver message).
+func respondError(c *gin.Context, status int, code, message string, extra gin.H) {
+	c.Header("Content-Language", requestLanguage(c, c.GetString(countryLocaleKey)))
+	body := gin.H{"error": message, "code": code}
+	for key, value := range extra {
+		body[key] = value
+	}
+	c.AbortWithStatusJSON(status, body)
+}
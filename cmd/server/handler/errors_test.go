@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"github.com/AleHts29/meli-challenge/pkg/store"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+)
+
+// TestErrorResponses verifica el status HTTP y el codigo estable de cada error, tanto de las
+// validaciones del handler como de los errores sentinela del servicio envueltos con %w.
+func TestErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	wrap := func(err error) error { return fmt.Errorf("error al obtener información del país: %w", err) }
+	specialPurpose := &ipinfo.SpecialPurposeError{Address: models.SpecialPurposeAddress{IP: "10.0.0.1", Network: "10.0.0.0/8"}}
+
+	tests := []struct {
+		name       string
+		target     string
+		blocked    string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"ip invalida", "/api/ip/999.1.1.1", "", nil, http.StatusBadRequest, CodeInvalidIP},
+		{"monto invalido", "/api/ip/181.0.0.1?to=USD&amount=-1", "", nil, http.StatusBadRequest, CodeInvalidAmount},
+		{"ip bloqueada", "/api/ip/181.0.0.1", "181.0.0.1", nil, http.StatusForbidden, CodeIPBlocked},
+		{"formato no soportado", "/api/ip/181.0.0.1?format=yaml", "", nil, http.StatusNotAcceptable, CodeUnsupportedFormat},
+		{"proposito especial", "/api/ip/181.0.0.1", "", specialPurpose, http.StatusUnprocessableEntity, CodeSpecialPurposeIP},
+		{"pais no operado", "/api/ip/181.0.0.1", "", wrap(ipinfo.ErrCountryNotOperated), http.StatusUnprocessableEntity, CodeCountryNotOperated},
+		{"pais no encontrado", "/api/ip/181.0.0.1", "", wrap(ipinfo.ErrCountryNotFound), http.StatusNotFound, CodeCountryNotFound},
+		{"ubicacion no encontrada", "/api/ip/181.0.0.1", "", wrap(ipinfo.ErrLocationNotFound), http.StatusNotFound, CodeLocationNotFound},
+		{"moneda desconocida", "/api/ip/181.0.0.1", "", wrap(ipinfo.ErrUnknownCurrency), http.StatusBadRequest, CodeUnknownCurrency},
+		{"ip sin geolocalizacion", "/api/ip/181.0.0.1", "", wrap(store.ErrIPNotFound), http.StatusBadGateway, CodeGeolocationNotFound},
+		{"ip2location fallo", "/api/ip/181.0.0.1", "", wrap(store.ErrLookupFailed), http.StatusBadGateway, CodeGeolocationFailed},
+		{"timeout", "/api/ip/181.0.0.1", "", wrap(api.ErrTimeout), http.StatusGatewayTimeout, CodeUpstreamTimeout},
+		{"rate limit", "/api/ip/181.0.0.1", "", wrap(api.ErrRateLimited), http.StatusBadGateway, CodeUpstreamRateLimited},
+		{"recurso no encontrado", "/api/ip/181.0.0.1", "", wrap(api.ErrNotFound), http.StatusBadGateway, CodeUpstreamNotFound},
+		{"no autorizado", "/api/ip/181.0.0.1", "", wrap(api.ErrUnauthorized), http.StatusBadGateway, CodeUpstreamUnauthorized},
+		{"respuesta invalida", "/api/ip/181.0.0.1", "", wrap(api.ErrBadResponse), http.StatusBadGateway, CodeUpstreamBadResponse},
+		{"no disponible", "/api/ip/181.0.0.1", "", wrap(api.ErrUnavailable), http.StatusBadGateway, CodeUpstreamUnavailable},
+		// El breaker abierto envuelve ErrCircuitOpen y api.ErrUnavailable (ver breaker.go)
+		{"breaker abierto", "/api/ip/181.0.0.1", "", wrap(fmt.Errorf("country: %w: %w", ipinfo.ErrCircuitOpen, api.ErrUnavailable)), http.StatusBadGateway, CodeUpstreamUnavailable},
+		{"plazo vencido", "/api/ip/181.0.0.1", "", wrap(context.DeadlineExceeded), http.StatusGatewayTimeout, CodeUpstreamTimeout},
+		{"error desconocido", "/api/ip/181.0.0.1", "", errors.New("fallo inesperado"), http.StatusInternalServerError, CodeInternalError},
+	}
+	for _, tt := range tests {
+		h := NewHandler(&countryService{blocked: tt.blocked, lookupErr: tt.err}, &config.Config{})
+		w := getCountry(h, tt.target)
+
+		var response ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: respuesta invalida: %v", tt.name, err)
+		}
+		if w.Code != tt.wantStatus || response.Code != tt.wantCode {
+			t.Errorf("%s: se obtuvo %d %s; se esperaba %d %s", tt.name, w.Code, response.Code, tt.wantStatus, tt.wantCode)
+		}
+		if response.Error == "" || response.Error == response.Code {
+			t.Errorf("%s: mensaje %q; se esperaba el mensaje traducido del codigo", tt.name, response.Error)
+		}
+		if tt.err != nil && response.Debug != tt.err.Error() {
+			t.Errorf("%s: debug %q; se esperaba %q", tt.name, response.Debug, tt.err.Error())
+		}
+	}
+}
+
+// TestErrorMappingCodes verifica que cada codigo de errorMappings este en errorCodes.
+func TestErrorMappingCodes(t *testing.T) {
+	codes := make(map[string]bool, len(errorCodes))
+	for _, code := range errorCodes {
+		codes[code] = true
+	}
+	for _, mapping := range errorMappings {
+		if !codes[mapping.code] {
+			t.Errorf("el codigo %s de %v no esta en errorCodes", mapping.code, mapping.target)
+		}
+	}
+}
@@ -1,93 +1,455 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/config"
 	"github.com/AleHts29/meli-challenge/internal/ipinfo"
 	"github.com/AleHts29/meli-challenge/internal/models"
 	"github.com/gin-gonic/gin"
 	"log"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Handler define el manejador HTTP para las solicitudes relacionadas con IPs y países.
 type Handler struct {
-	Service ipinfo.Service
+	Service  ipinfo.Service
+	cfg      *config.Config
+	clientIP *clientIPResolver
+}
+
+// LookupRequest es el cuerpo de POST /api/ip/lookup.
+type LookupRequest struct {
+	IPs []string `json:"ips" binding:"required"`
+}
+
+// BlockRequest es el cuerpo de POST /api/ip/block.
+type BlockRequest struct {
+	IPs        []string `json:"ip" binding:"required"`
+	TTLSeconds int64    `json:"ttl_seconds"` // opcional, 0 = bloqueo permanente
+}
+
+// CallerCountryResponse es la respuesta de GET /api/ip/me.
+type CallerCountryResponse struct {
+	IP          string              `json:"ip"`
+	CountryInfo *models.CountryInfo `json:"country_info"`
+}
+
+// BlockResponse es la respuesta de POST /api/ip/block.
+type BlockResponse struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// BlockListResponse es la respuesta de GET /api/ip/block.
+type BlockListResponse struct {
+	Count      int                `json:"count"`
+	BlockedIPs []ipinfo.BlockedIP `json:"blocked_ips"`
+}
+
+// UnblockResponse es la respuesta de DELETE /api/ip/block/:ip.
+type UnblockResponse struct {
+	Message string `json:"message"`
+	IP      string `json:"ip"`
 }
 
 // NewHandler crea un nuevo manejador para las solicitudes relacionadas con IPs y países.
-func NewHandler(s ipinfo.Service) *Handler {
-	return &Handler{Service: s}
+func NewHandler(s ipinfo.Service, cfg *config.Config) *Handler {
+	resolver := &clientIPResolver{header: cfg.TrustedProxyHeader}
+	for _, cidr := range cfg.TrustedProxies {
+		// los CIDRs ya fueron validados al cargar la configuracion
+		if ipNet, err := config.ParseCIDR(cidr); err == nil {
+			resolver.trusted = append(resolver.trusted, ipNet)
+		}
+	}
+	return &Handler{Service: s, cfg: cfg, clientIP: resolver}
+}
+
+// RequestDeadline limita la duracion de cada solicitud a cfg.RequestTimeout. El plazo se
+// propaga con el contexto de la solicitud hasta las consultas a las APIs externas, que
+// se cancelan al vencer o cuando el cliente se desconecta. Con RequestTimeout 0 no hay plazo.
+func (h *Handler) RequestDeadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.cfg.RequestTimeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.RequestTimeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
 }
 
-// GetCountryByIP devuelve información sobre un país a partir de una IP.
+// GetCountryByIP devuelve información sobre un país a partir de una IP, en el formato
+// negociado con Accept o ?format= (JSON por defecto).
 func (h *Handler) GetCountryByIP() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.Param("ip")
-		if ip == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Debe proporcionar una IP"})
+		format, ok := negotiateFormat(c)
+		if !ok {
 			return
 		}
 
-		// Validacion de formato de la IP
-		if net.ParseIP(ip) == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "La IP proporcionada no es válida", "ip": ip})
+		countryInfo, ok := h.countryByIP(c)
+		if !ok {
+			return
+		}
+
+		// Se devuelve la información del país en el formato pedido.
+		renderOne(c, format, lookupRecords, countryItem(c.Param("ip"), countryInfo, countryInfo))
+	}
+}
+
+// countryByIP valida la IP del path y obtiene la información del país, con la conversion
+// opcional de ?to=&amount=. Si la consulta no es posible escribe la respuesta de error y retorna false.
+func (h *Handler) countryByIP(c *gin.Context) (*models.CountryInfo, bool) {
+	ip := c.Param("ip")
+	if ip == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidIP, message(c, msgIPRequired), nil)
+		return nil, false
+	}
+
+	// Validacion de formato de la IP
+	if net.ParseIP(ip) == nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidIP, message(c, CodeInvalidIP), gin.H{"ip": ip})
+		return nil, false
+	}
+
+	to, amount, ok := conversionParams(c)
+	if !ok {
+		respondError(c, http.StatusBadRequest, CodeInvalidAmount, message(c, msgInvalidAmount, c.Query("amount")), nil)
+		return nil, false
+	}
+
+	countryInfo, ok := h.lookupCountry(c, ip)
+	if !ok || len(to) == 0 {
+		return countryInfo, ok
+	}
+
+	// Conversion opcional del monto a las monedas pedidas en ?to=
+	withConversions := *countryInfo
+	withConversions.Warnings = append([]models.Warning(nil), countryInfo.Warnings...)
+
+	var err error
+	if countryInfo.CurrencyId == "" {
+		// respuesta parcial sin detalle del pais: no se conoce la moneda de origen
+		err = errors.New("moneda del país desconocida")
+	} else {
+		withConversions.Conversions, err = h.Service.ConvertAmount(c.Request.Context(), countryInfo.CurrencyId, to, amount)
+	}
+
+	switch {
+	case errors.Is(err, ipinfo.ErrUnknownCurrency), err != nil && lookupOptions(c).Strict:
+		respondServiceError(c, err)
+		return nil, false
+	case err != nil:
+		withConversions.AddWarning("conversions", err)
+	}
+	return localizeWarnings(requestLanguage(c, countryInfo.Locale), &withConversions), true
+}
+
+// conversionParams lee los parametros ?to=EUR,BRL&amount=1500, sin monedas repetidas. amount por defecto es 1.
+// Retorna false si amount no es un monto valido.
+func conversionParams(c *gin.Context) ([]string, float64, bool) {
+	var to []string
+	seen := make(map[string]bool)
+	for _, currency := range strings.Split(c.Query("to"), ",") {
+		// las monedas repetidas se convierten una sola vez
+		if currency = strings.ToUpper(strings.TrimSpace(currency)); currency != "" && !seen[currency] {
+			seen[currency] = true
+			to = append(to, currency)
+		}
+	}
+
+	amount := 1.0
+	if value := c.Query("amount"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || math.IsInf(parsed, 0) || math.IsNaN(parsed) {
+			return nil, 0, false
+		}
+		amount = parsed
+	}
+	return to, amount, true
+}
+
+// GetCallerCountry devuelve información sobre el país desde el que se origina la solicitud.
+// La IP del cliente se toma de X-Forwarded-For / Forwarded solo si la conexion viene de un proxy de confianza.
+func (h *Handler) GetCallerCountry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
 			return
 		}
 
-		// Verifica si la IP esta bloqueada.
-		if h.Service.IsBlocked(ip) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "IP está bloqueda, no es posible visualizar la informacion"})
+		clientIP := h.clientIP.resolve(c.Request)
+		if clientIP == nil {
+			respondError(c, http.StatusBadRequest, CodeClientIPUnknown, message(c, CodeClientIPUnknown), nil)
 			return
 		}
+		ip := clientIP.String()
 
-		countryInfo, err := h.Service.GetCountryDataByIP(ip)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		countryInfo, ok := h.lookupCountry(c, ip)
+		if !ok {
 			return
 		}
 
-		// Se devuelve la información del país en formato JSON.
-		c.JSON(http.StatusOK, countryInfo)
+		renderOne(c, format, lookupRecords, countryItem(ip, countryInfo, CallerCountryResponse{IP: ip, CountryInfo: countryInfo}))
+	}
+}
+
+// lookupCountry aplica la lista de bloqueos y obtiene la información del país de una IP valida.
+// Si la consulta no es posible escribe la respuesta de error y retorna false.
+func (h *Handler) lookupCountry(c *gin.Context, ip string) (*models.CountryInfo, bool) {
+	// Verifica si la IP esta bloqueada.
+	if h.Service.IsBlocked(ip) {
+		h.reportLookupDenied(c, ip)
+		respondError(c, http.StatusForbidden, CodeIPBlocked, message(c, CodeIPBlocked), nil)
+		return nil, false
+	}
+
+	countryInfo, err := h.Service.GetCountryDataByIP(c.Request.Context(), ip, lookupOptions(c))
+	if err != nil {
+		respondServiceError(c, err)
+		return nil, false
+	}
+
+	// Sin Accept-Language los mensajes siguientes usan el idioma del país
+	c.Set(countryLocaleKey, countryInfo.Locale)
+	markStale(c, countryInfo)
+	return localizeWarnings(requestLanguage(c, countryInfo.Locale), countryInfo), true
+}
+
+// staleWarning es el encabezado Warning (RFC 7234) de las respuestas con datos vencidos.
+const staleWarning = `110 - "Response is Stale"`
+
+// markStale agrega el encabezado Warning si la información incluye datos de MELI servidos vencidos
+// de la caché.
+func markStale(c *gin.Context, countryInfo *models.CountryInfo) {
+	if countryInfo.Stale {
+		c.Header("Warning", staleWarning)
+	}
+}
+
+// lookupOptions lee las opciones de resolucion de la query. Con ?strict=true la consulta
+// falla si alguna API de MELI falla, en lugar de devolver una respuesta parcial.
+func lookupOptions(c *gin.Context) ipinfo.LookupOptions {
+	strict, _ := strconv.ParseBool(c.Query("strict"))
+	return ipinfo.LookupOptions{Strict: strict}
+}
+
+// reportLookupDenied notifica al servicio un intento de consulta sobre una IP bloqueada.
+func (h *Handler) reportLookupDenied(c *gin.Context, ip string) {
+	var requester string
+	if clientIP := h.clientIP.resolve(c.Request); clientIP != nil {
+		requester = clientIP.String()
+	}
+	h.Service.ReportLookupDenied(ip, models.LookupDeniedPayload{
+		ClientIP:  requester,
+		UserAgent: c.Request.UserAgent(),
+	})
+}
+
+// LookupIPs resuelve un lote de IPs. En JSON responde con los resultados agrupados por estado;
+// en los demas formatos (NDJSON, CSV, XML, protobuf) escribe un resultado por vez, a medida que
+// se resuelven. "?stream=true" equivale a "?format=ndjson".
+func (h *Handler) LookupIPs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+
+		results, ok := h.lookupBatch(c)
+		if !ok {
+			return
+		}
+
+		if format != formatJSON {
+			w := newResponseWriter(c, format, lookupRecords, true)
+			for result := range results {
+				w.write(lookupItem(result, result))
+			}
+			w.close()
+			return
+		}
+
+		response := models.NewBatchLookupResponse()
+		for result := range results {
+			response.Add(result)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// lookupBatch valida el cuerpo de una consulta en lote y comienza a resolverla. El canal
+// devuelto emite primero las IPs invalidas o bloqueadas y luego las resueltas por el servicio,
+// con su codigo de error. Si el cuerpo no es valido escribe la respuesta de error y retorna false.
+func (h *Handler) lookupBatch(c *gin.Context) (<-chan models.LookupResult, bool) {
+	var req LookupRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, message(c, msgLookupBodyInvalid), gin.H{"debug": err.Error()})
+		return nil, false
+	}
+
+	if len(req.IPs) == 0 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, message(c, msgLookupEmpty), nil)
+		return nil, false
+	}
+
+	if len(req.IPs) > h.cfg.BatchMaxIPs {
+		respondError(c, http.StatusRequestEntityTooLarge, CodeBatchTooLarge, message(c, CodeBatchTooLarge), gin.H{"max": h.cfg.BatchMaxIPs})
+		return nil, false
+	}
+
+	// Las IPs invalidas o bloqueadas se resuelven sin consultar el servicio
+	var pending []models.LookupResult
+	var toResolve []string
+	seen := make(map[string]bool, len(req.IPs))
+	for _, ip := range req.IPs {
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+
+		switch {
+		case net.ParseIP(ip) == nil:
+			pending = append(pending, models.LookupResult{IP: ip, Status: models.LookupInvalid, Error: message(c, CodeInvalidIP), Code: CodeInvalidIP})
+		case h.Service.IsBlocked(ip):
+			h.reportLookupDenied(c, ip)
+			pending = append(pending, models.LookupResult{IP: ip, Status: models.LookupBlocked, Error: message(c, CodeIPBlocked), Code: CodeIPBlocked})
+		default:
+			toResolve = append(toResolve, ip)
+		}
+	}
+
+	resolved := h.Service.LookupBatch(c.Request.Context(), toResolve, h.cfg.BatchConcurrency, lookupOptions(c))
+
+	results := make(chan models.LookupResult, len(pending))
+	go func() {
+		defer close(results)
+		for _, result := range pending {
+			results <- result
+		}
+		for result := range resolved {
+			results <- localizeResult(c, withErrorCode(result))
+		}
+	}()
+	return results, true
+}
+
+// GetStats devuelve la cantidad de consultas por país y las distancias mas cercana,
+// mas lejana y promedio (ponderada por consultas) al punto de referencia.
+func (h *Handler) GetStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, h.Service.Stats())
+	}
+}
+
+// GetHealth devuelve el estado del servicio y de los circuit breakers de las APIs de MELI.
+// Responde 200 tambien si esta degradado: el servicio sigue respondiendo con datos en caché o parciales.
+func (h *Handler) GetHealth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, h.Service.Health())
+	}
+}
+
+// ListBlockedIPs devuelve los bloqueos vigentes en el formato negociado con Accept o ?format=.
+func (h *Handler) ListBlockedIPs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+
+		blocked := h.Service.BlockedIPs()
+		if format == formatJSON {
+			if blocked == nil {
+				blocked = []ipinfo.BlockedIP{}
+			}
+			c.JSON(http.StatusOK, BlockListResponse{Count: len(blocked), BlockedIPs: blocked})
+			return
+		}
+
+		w := newResponseWriter(c, format, blockRecords, true)
+		for _, entry := range blocked {
+			w.write(blockItem(entry))
+		}
+		w.close()
 	}
 }
 
 // BlockIPs bloquea una o varias IPs para evitar que se consulte informacion del pais de origen
 func (h *Handler) BlockIPs() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			IPs []string `json:"ip" binding:"required"`
-		}
+		var req BlockRequest
 
 		// Intentar parsear el cuerpo de la solicitud
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Debe proporcionar una IP válida en el cuerpo de la solicitud", "details": err.Error()})
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, message(c, msgBlockBodyInvalid), gin.H{"debug": err.Error()})
+			return
+		}
+
+		if req.TTLSeconds < 0 {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, message(c, msgNegativeTTL), nil)
 			return
 		}
 
 		if len(req.IPs) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Lista de IPs vacia, agregar las IPs que desea bloquear"})
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, message(c, msgBlockEmpty), nil)
 			return
 		}
 
 		// Validar formato de la lista de IPs
 		for _, ip := range req.IPs {
 			if net.ParseIP(ip) == nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "La IP proporcionada no es válida", "ip": ip})
+				respondError(c, http.StatusBadRequest, CodeInvalidIP, message(c, CodeInvalidIP), gin.H{"ip": ip})
 				return
 			}
 		}
 
 		// Se bloquea la IP
+		ttl := time.Duration(req.TTLSeconds) * time.Second
 		for _, ip := range req.IPs {
-			if err := h.Service.BlockIP(ip); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al guardar la lista de IPs bloqueadas", "details": err.Error()})
+			if err := h.Service.BlockIP(ip, ttl); err != nil {
+				respondError(c, http.StatusInternalServerError, CodePersistenceFailed, message(c, CodePersistenceFailed), gin.H{"debug": err.Error()})
 				return
 			}
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "bloqueo exitoso", "count": len(req.IPs)})
+		c.JSON(http.StatusOK, BlockResponse{Message: message(c, msgBlocked), Count: len(req.IPs)})
+	}
+}
+
+// UnblockIP quita una IP de la lista de bloqueos
+func (h *Handler) UnblockIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.Param("ip")
+		if net.ParseIP(ip) == nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidIP, message(c, CodeInvalidIP), gin.H{"ip": ip})
+			return
+		}
+
+		found, err := h.Service.UnblockIP(ip)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodePersistenceFailed, message(c, CodePersistenceFailed), gin.H{"debug": err.Error()})
+			return
+		}
+		if !found {
+			respondError(c, http.StatusNotFound, CodeIPNotBlocked, message(c, CodeIPNotBlocked), gin.H{"ip": ip})
+			return
+		}
+
+		c.JSON(http.StatusOK, UnblockResponse{Message: message(c, msgUnblocked), IP: ip})
 	}
 }
 
@@ -112,7 +474,15 @@ func (h *Handler) NotifyBlockedIPs() gin.HandlerFunc {
 		// Loop para enviar eventos
 		for {
 			select {
-			case event := <-clientChan: // Escucha eeventos en el canal principal
+			case event, ok := <-clientChan: // Escucha eeventos en el canal principal
+				if !ok {
+					// El servicio cerro la suscripcion porque el cliente no recibia los eventos a tiempo
+					event = models.Event{
+						Event:     models.EventSubscriptionClosed,
+						Timestamp: time.Now().UTC(),
+						Payload:   models.SubscriptionClosedPayload{Reason: "slow_consumer"},
+					}
+				}
 				_, err := fmt.Fprintf(c.Writer, "data: %s\n\n", formatEvent(event))
 				if err != nil {
 					log.Printf("Error: al enviar evento: %v", err)
@@ -120,18 +490,19 @@ func (h *Handler) NotifyBlockedIPs() gin.HandlerFunc {
 				}
 				//	Forzar la escritura del buffer al cliente
 				c.Writer.Flush()
+				if !ok {
+					return
+				}
 			case <-disconnectChan:
 				log.Println("Cliente desconectado")
 				return
-			default:
 			}
-
 		}
 	}
 }
 
 // formatEvent convierte un evento a JSON.
-func formatEvent(event models.BlockEvent) string {
+func formatEvent(event models.Event) string {
 	data, _ := json.Marshal(event)
 	return string(data)
 }
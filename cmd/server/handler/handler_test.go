@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countryService es un ipinfo.Service que responde siempre Argentina, salvo que lookupErr no sea
+// nil, y que convierte montos a USD, EUR y BRL con cotizacion 1, o falla con convertErr. Los demas metodos no se usan.
+type countryService struct {
+	ipinfo.Service
+	blocked    string
+	lookupErr  error
+	convertErr error
+}
+
+func (s *countryService) IsBlocked(ip string) bool {
+	return ip == s.blocked
+}
+
+func (s *countryService) ReportLookupDenied(ip string, payload models.LookupDeniedPayload) {}
+
+func (s *countryService) GetCountryDataByIP(ctx context.Context, ip string, opts ipinfo.LookupOptions) (*models.CountryInfo, error) {
+	if s.lookupErr != nil {
+		return nil, s.lookupErr
+	}
+	return &models.CountryInfo{Country: models.Country{ID: "AR", Name: "Argentina", CurrencyId: "ARS"}}, nil
+}
+
+func (s *countryService) ConvertAmount(ctx context.Context, from string, to []string, amount float64) ([]models.CurrencyConversion, error) {
+	if s.convertErr != nil {
+		return nil, s.convertErr
+	}
+	conversions := make([]models.CurrencyConversion, len(to))
+	for i, target := range to {
+		if target != "USD" && target != "EUR" && target != "BRL" {
+			return nil, fmt.Errorf("'%s': %w", target, ipinfo.ErrUnknownCurrency)
+		}
+		conversions[i] = models.CurrencyConversion{From: from, To: target, Rate: 1, Amount: amount, ConvertedAmount: amount}
+	}
+	return conversions, nil
+}
+
+// slowRepository simula APIs que tardan latency en responder, o hasta que se cancele la
+// consulta. Si slowIP es false IP2Location responde de inmediato. Cuenta las consultas canceladas.
+type slowRepository struct {
+	latency  time.Duration
+	slowIP   bool
+	canceled atomic.Int64
+}
+
+func (r *slowRepository) wait(ctx context.Context) error {
+	select {
+	case <-time.After(r.latency):
+		return nil
+	case <-ctx.Done():
+		r.canceled.Add(1)
+		return ctx.Err()
+	}
+}
+
+func (r *slowRepository) GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error) {
+	if r.slowIP {
+		if err := r.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return &models.IPInfo{IP: ip, CountryCode: "AR", CountryName: "Argentina"}, nil
+}
+
+func (r *slowRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	if err := r.wait(ctx); err != nil {
+		return nil, err
+	}
+	return []models.Country{{ID: "AR", Name: "Argentina"}}, nil
+}
+
+func (r *slowRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	if err := r.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &models.CountryInfo{Country: models.Country{ID: countryID, Name: "Argentina", CurrencyId: "ARS"}}, nil
+}
+
+func (r *slowRepository) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	return nil, r.wait(ctx)
+}
+
+func (r *slowRepository) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	return nil, r.wait(ctx)
+}
+
+func (r *slowRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	if err := r.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &models.CurrencyExchange{Rate: 0.001}, nil
+}
+
+func (r *slowRepository) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
+	return nil, r.wait(ctx)
+}
+
+// TestRequestDeadline verifica que al vencer REQUEST_TIMEOUT la solicitud responde de inmediato
+// con 504 y que luego se cancelan las consultas en curso a las APIs externas, tanto si tarda la
+// geolocalizacion como si tarda MELI.
+func TestRequestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		slowIP bool
+		query  string
+	}{
+		{"geolocalizacion", true, ""},
+		{"meli", false, ""},
+		{"meli estricto", false, "?strict=true"},
+	}
+	for _, tt := range tests {
+		repo := &slowRepository{latency: 5 * time.Second, slowIP: tt.slowIP}
+		cfg := &config.Config{
+			RequestTimeout:          50 * time.Millisecond,
+			BreakerFailureThreshold: 100,
+			BreakerOpenTimeout:      time.Minute,
+			BlockedIPsFilePath:      filepath.Join(t.TempDir(), "blocked_ips.json"),
+		}
+		h := NewHandler(ipinfo.NewService(repo, cfg), cfg)
+		router := gin.New()
+		router.Use(h.RequestDeadline())
+		router.GET("/api/ip/:ip", h.GetCountryByIP())
+
+		start := time.Now()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ip/181.0.0.1"+tt.query, nil))
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: la solicitud tardo %v; se esperaba que terminara al vencer el plazo", tt.name, elapsed)
+		}
+		var response ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusGatewayTimeout || response.Code != CodeUpstreamTimeout {
+			t.Errorf("%s: se obtuvo %d %s; se esperaba %d %s", tt.name, w.Code, w.Body, http.StatusGatewayTimeout, CodeUpstreamTimeout)
+		}
+
+		// La consulta coalescida se cancela cuando deja de esperarla el ultimo llamador
+		for deadline := time.Now().Add(time.Second); repo.canceled.Load() == 0 && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if repo.canceled.Load() == 0 {
+			t.Errorf("%s: no se cancelo ninguna consulta a las APIs externas", tt.name)
+		}
+	}
+}
+
+// getCountry envia una solicitud a GET /api/ip/:ip.
+func getCountry(h *Handler, target string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/api/ip/:ip", h.GetCountryByIP())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// TestConversionFailure verifica que, si falla la conversion de ?to=, la respuesta es parcial con
+// un aviso de la fuente "conversions", salvo en modo estricto, donde falla con el error mapeado.
+func TestConversionFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(&countryService{convertErr: api.ErrUnavailable}, &config.Config{})
+
+	w := getCountry(h, "/api/ip/181.0.0.1?to=USD&amount=10")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var info models.CountryInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if !info.Partial || len(info.Warnings) != 1 || info.Warnings[0].Source != "conversions" || info.Warnings[0].Code != CodeUpstreamUnavailable || len(info.Conversions) != 0 {
+		t.Errorf("se esperaba una respuesta parcial con un aviso de conversions, se obtuvo %s", w.Body)
+	}
+
+	w = getCountry(h, "/api/ip/181.0.0.1?to=USD&amount=10&strict=true")
+	var response ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadGateway || response.Code != CodeUpstreamUnavailable {
+		t.Errorf("en modo estricto se obtuvo %d %s; se esperaba %d %s", w.Code, response.Code, http.StatusBadGateway, CodeUpstreamUnavailable)
+	}
+}
+
+// TestConversionParams verifica la lectura de ?to= y ?amount=.
+func TestConversionParams(t *testing.T) {
+	tests := []struct {
+		query  string
+		to     []string
+		amount float64
+		ok     bool
+	}{
+		{"", nil, 1, true},
+		{"to=eur,%20brl&amount=1500", []string{"EUR", "BRL"}, 1500, true},
+		{"to=EUR,eur,BRL,EUR", []string{"EUR", "BRL"}, 1, true},
+		{"to=,,", nil, 1, true},
+		{"to=EUR&amount=0", []string{"EUR"}, 0, true},
+		{"to=EUR&amount=-1", nil, 0, false},
+		{"to=EUR&amount=abc", nil, 0, false},
+		{"to=EUR&amount=NaN", nil, 0, false},
+		{"to=EUR&amount=Inf", nil, 0, false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/ip/181.0.0.1?"+tt.query, nil)
+		to, amount, ok := conversionParams(c)
+		if !reflect.DeepEqual(to, tt.to) || amount != tt.amount || ok != tt.ok {
+			t.Errorf("conversionParams(%q) = %v, %v, %v; se esperaba %v, %v, %v", tt.query, to, amount, ok, tt.to, tt.amount, tt.ok)
+		}
+	}
+}
+
+// TestConversions verifica que las monedas repetidas de ?to= se convierten una vez y que una moneda
+// desconocida responde 400 UNKNOWN_CURRENCY, tambien fuera del modo estricto.
+func TestConversions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(&countryService{}, &config.Config{})
+
+	w := getCountry(h, "/api/ip/181.0.0.1?to=EUR,eur,BRL&amount=10")
+	var info models.CountryInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if len(info.Conversions) != 2 || info.Conversions[0].To != "EUR" || info.Conversions[1].To != "BRL" || info.Partial {
+		t.Errorf("conversiones %+v; se esperaban EUR y BRL una vez", info.Conversions)
+	}
+
+	for _, query := range []string{"?to=EUR,XXX", "?to=XXX&strict=true"} {
+		w = getCountry(h, "/api/ip/181.0.0.1"+query)
+		var response ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusBadRequest || response.Code != CodeUnknownCurrency {
+			t.Errorf("%s: se obtuvo %d %s; se esperaba %d %s", query, w.Code, w.Body, http.StatusBadRequest, CodeUnknownCurrency)
+		}
+	}
+}
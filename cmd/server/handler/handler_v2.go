@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"github.com/AleHts29/meli-challenge/cmd/server/handler/v2"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+////////////////////////////////
+// *** API V2 ***
+// Las rutas de /api/v2 responden con los DTOs del paquete v2. Validaciones, bloqueos y
+// errores son los mismos que en /api/ip (v1), que se mantiene hasta que migren los clientes.
+
+// GetCountryByIPV2 devuelve la información de una IP con el contrato de v2.
+func (h *Handler) GetCountryByIPV2() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+
+		countryInfo, ok := h.countryByIP(c)
+		if !ok {
+			return
+		}
+
+		ip := c.Param("ip")
+		renderOne(c, format, lookupRecords, countryItem(ip, countryInfo, v2.NewIPLookup(ip, countryInfo)))
+	}
+}
+
+// GetCallerCountryV2 devuelve la información de la IP que origina la solicitud con el contrato de v2.
+func (h *Handler) GetCallerCountryV2() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+
+		clientIP := h.clientIP.resolve(c.Request)
+		if clientIP == nil {
+			respondError(c, http.StatusBadRequest, CodeClientIPUnknown, message(c, CodeClientIPUnknown), nil)
+			return
+		}
+		ip := clientIP.String()
+
+		countryInfo, ok := h.lookupCountry(c, ip)
+		if !ok {
+			return
+		}
+
+		renderOne(c, format, lookupRecords, countryItem(ip, countryInfo, v2.NewIPLookup(ip, countryInfo)))
+	}
+}
+
+// LookupIPsV2 resuelve un lote de IPs con el contrato de v2. Igual que en v1, los formatos
+// distintos de JSON se escriben a medida que se resuelven las IPs.
+func (h *Handler) LookupIPsV2() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+
+		results, ok := h.lookupBatch(c)
+		if !ok {
+			return
+		}
+
+		if format != formatJSON {
+			w := newResponseWriter(c, format, lookupRecords, true)
+			for result := range results {
+				w.write(lookupItem(result, v2.NewLookupResult(result)))
+			}
+			w.close()
+			return
+		}
+
+		response := v2.NewBatchLookupResponse()
+		for result := range results {
+			response.Add(result)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// batchService es un ipinfo.Service que resuelve los lotes sin consultar APIs externas y
+// registra las IPs que recibe. Los demas metodos no se usan.
+type batchService struct {
+	ipinfo.Service
+	blocked  map[string]bool
+	received [][]string
+}
+
+func (s *batchService) LookupBatch(ctx context.Context, ips []string, concurrency int, opts ipinfo.LookupOptions) <-chan models.LookupResult {
+	s.received = append(s.received, ips)
+	results := make(chan models.LookupResult, len(ips))
+	for _, ip := range ips {
+		results <- models.LookupResult{IP: ip, Status: models.LookupOK, Data: &models.CountryInfo{Country: models.Country{ID: "AR"}}}
+	}
+	close(results)
+	return results
+}
+
+func (s *batchService) IsBlocked(ip string) bool {
+	return s.blocked[ip]
+}
+
+func (s *batchService) ReportLookupDenied(ip string, payload models.LookupDeniedPayload) {}
+
+// postLookup envia un lote a POST /api/ip/lookup.
+func postLookup(h *Handler, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/api/ip/lookup", h.LookupIPs())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/ip/lookup", bytes.NewBufferString(body)))
+	return w
+}
+
+// TestLookupBatchDedup verifica que las IPs repetidas se informan una vez y que las invalidas o
+// bloqueadas no llegan al servicio.
+func TestLookupBatchDedup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &batchService{blocked: map[string]bool{"5.5.5.5": true}}
+	h := NewHandler(service, &config.Config{BatchMaxIPs: 10, BatchConcurrency: 2})
+
+	w := postLookup(h, `{"ips": ["1.1.1.1", "1.1.1.1", "bad", "5.5.5.5", "2.2.2.2", "bad", "5.5.5.5", "2.2.2.2"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if want := [][]string{{"1.1.1.1", "2.2.2.2"}}; !reflect.DeepEqual(service.received, want) {
+		t.Fatalf("el servicio recibio %v, se esperaba %v", service.received, want)
+	}
+	var response models.BatchLookupResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Count != 4 || len(response.Found) != 2 || !reflect.DeepEqual(response.Invalid, []string{"bad"}) || !reflect.DeepEqual(response.Blocked, []string{"5.5.5.5"}) {
+		t.Fatalf("respuesta inesperada: %s", w.Body)
+	}
+}
+
+// TestLookupBatchTooLarge verifica que un lote que supera BATCH_MAX_IPS se rechaza sin resolverlo,
+// contando las IPs repetidas.
+func TestLookupBatchTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &batchService{}
+	h := NewHandler(service, &config.Config{BatchMaxIPs: 2, BatchConcurrency: 2})
+
+	if w := postLookup(h, `{"ips": ["1.1.1.1", "1.1.1.1"]}`); w.Code != http.StatusOK {
+		t.Fatalf("un lote de %d IPs deberia aceptarse, status %d", 2, w.Code)
+	}
+	w := postLookup(h, `{"ips": ["1.1.1.1", "2.2.2.2", "1.1.1.1"]}`)
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusRequestEntityTooLarge || response.Code != CodeBatchTooLarge || response.Max != 2 || len(service.received) != 1 {
+		t.Fatalf("se esperaba 413 %s sin consultar el servicio, se obtuvo %d: %s", CodeBatchTooLarge, w.Code, w.Body)
+	}
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+	"strings"
+)
+
+// Idiomas de los mensajes. El primero es el idioma por defecto.
+const (
+	langSpanish    = "es"
+	langPortuguese = "pt"
+	langEnglish    = "en"
+)
+
+var (
+	supportedLanguages = []string{langSpanish, langPortuguese, langEnglish}
+	languageMatcher    = language.NewMatcher([]language.Tag{language.Spanish, language.Portuguese, language.English})
+)
+
+// countryLocaleKey guarda en el contexto de gin el Locale de MELI del país consultado ("es_AR", "pt_BR").
+const countryLocaleKey = "country_locale"
+
+// Claves de los mensajes que no corresponden a un unico codigo de error. Los mensajes genericos
+// de cada codigo (usados para los errores del servicio) tienen como clave el propio codigo.
+const (
+	msgIPRequired        = "ip_required"
+	msgInvalidAmount     = "invalid_amount"
+	msgLookupBodyInvalid = "lookup_body_invalid"
+	msgLookupEmpty       = "lookup_empty"
+	msgBlockBodyInvalid  = "block_body_invalid"
+	msgBlockEmpty        = "block_empty"
+	msgNegativeTTL       = "negative_ttl"
+	msgFormatUnsupported = "format_unsupported"
+	msgAcceptUnsupported = "accept_unsupported"
+	msgSupportedFormats  = "supported_formats"
+	msgBlocked           = "blocked"
+	msgUnblocked         = "unblocked"
+)
+
+// Los avisos de respuestas parciales se traducen segun la fuente que fallo, con la clave
+// warningPrefix + fuente ("warning_country").
+const warningPrefix = "warning_"
+
+// messages es el catalogo de mensajes por clave e idioma. Los mensajes pueden tener verbos de fmt.
+var messages = map[string]map[string]string{
+	msgIPRequired: {
+		langSpanish:    "Debe proporcionar una IP",
+		langPortuguese: "É necessário informar um IP",
+		langEnglish:    "An IP address is required",
+	},
+	msgInvalidAmount: {
+		langSpanish:    "El monto '%s' no es válido",
+		langPortuguese: "O valor '%s' não é válido",
+		langEnglish:    "The amount '%s' is not valid",
+	},
+	msgLookupBodyInvalid: {
+		langSpanish:    "Debe proporcionar una lista de IPs en el cuerpo de la solicitud",
+		langPortuguese: "É necessário informar uma lista de IPs no corpo da requisição",
+		langEnglish:    "A list of IP addresses is required in the request body",
+	},
+	msgLookupEmpty: {
+		langSpanish:    "Lista de IPs vacia, agregar las IPs que desea consultar",
+		langPortuguese: "Lista de IPs vazia, adicione os IPs que deseja consultar",
+		langEnglish:    "The IP list is empty, add the IP addresses to look up",
+	},
+	msgBlockBodyInvalid: {
+		langSpanish:    "Debe proporcionar una IP válida en el cuerpo de la solicitud",
+		langPortuguese: "É necessário informar um IP válido no corpo da requisição",
+		langEnglish:    "A valid IP address is required in the request body",
+	},
+	msgBlockEmpty: {
+		langSpanish:    "Lista de IPs vacia, agregar las IPs que desea bloquear",
+		langPortuguese: "Lista de IPs vazia, adicione os IPs que deseja bloquear",
+		langEnglish:    "The IP list is empty, add the IP addresses to block",
+	},
+	msgNegativeTTL: {
+		langSpanish:    "ttl_seconds no puede ser negativo",
+		langPortuguese: "ttl_seconds não pode ser negativo",
+		langEnglish:    "ttl_seconds cannot be negative",
+	},
+	msgFormatUnsupported: {
+		langSpanish:    "El formato '%s' no está soportado",
+		langPortuguese: "O formato '%s' não é suportado",
+		langEnglish:    "The format '%s' is not supported",
+	},
+	msgAcceptUnsupported: {
+		langSpanish:    "Ninguno de los tipos de contenido de Accept está soportado",
+		langPortuguese: "Nenhum dos tipos de conteúdo de Accept é suportado",
+		langEnglish:    "None of the content types in Accept is supported",
+	},
+	msgSupportedFormats: {
+		langSpanish:    "formatos soportados: %s",
+		langPortuguese: "formatos suportados: %s",
+		langEnglish:    "supported formats: %s",
+	},
+	msgBlocked: {
+		langSpanish:    "bloqueo exitoso",
+		langPortuguese: "bloqueio realizado",
+		langEnglish:    "blocked successfully",
+	},
+	msgUnblocked: {
+		langSpanish:    "desbloqueo exitoso",
+		langPortuguese: "desbloqueio realizado",
+		langEnglish:    "unblocked successfully",
+	},
+
+	warningPrefix + "countries": {
+		langSpanish:    "No fue posible obtener la lista de países de MercadoLibre",
+		langPortuguese: "Não foi possível obter a lista de países do MercadoLivre",
+		langEnglish:    "The MercadoLibre country list could not be retrieved",
+	},
+	warningPrefix + "country": {
+		langSpanish:    "No fue posible obtener el detalle del país",
+		langPortuguese: "Não foi possível obter os detalhes do país",
+		langEnglish:    "The country details could not be retrieved",
+	},
+	warningPrefix + "currency_conversion": {
+		langSpanish:    "No fue posible obtener la cotización a USD",
+		langPortuguese: "Não foi possível obter a cotação em USD",
+		langEnglish:    "The USD exchange rate could not be retrieved",
+	},
+	warningPrefix + "currencies": {
+		langSpanish:    "No fue posible obtener la lista de monedas para formatear los montos",
+		langPortuguese: "Não foi possível obter a lista de moedas para formatar os valores",
+		langEnglish:    "The currency list could not be retrieved to format the amounts",
+	},
+	warningPrefix + "conversions": {
+		langSpanish:    "No fue posible convertir el monto a las monedas pedidas",
+		langPortuguese: "Não foi possível converter o valor para as moedas solicitadas",
+		langEnglish:    "The amount could not be converted to the requested currencies",
+	},
+
+	CodeInvalidRequest: {
+		langSpanish:    "La solicitud no es válida",
+		langPortuguese: "A requisição não é válida",
+		langEnglish:    "The request is not valid",
+	},
+	CodeInvalidIP: {
+		langSpanish:    "La IP proporcionada no es válida",
+		langPortuguese: "O IP informado não é válido",
+		langEnglish:    "The IP address is not valid",
+	},
+	CodeInvalidAmount: {
+		langSpanish:    "El monto no es válido",
+		langPortuguese: "O valor não é válido",
+		langEnglish:    "The amount is not valid",
+	},
+	CodeIPBlocked: {
+		langSpanish:    "IP está bloqueda, no es posible visualizar la informacion",
+		langPortuguese: "O IP está bloqueado, não é possível visualizar as informações",
+		langEnglish:    "The IP address is blocked, its information cannot be displayed",
+	},
+	CodeIPNotBlocked: {
+		langSpanish:    "La IP no se encuentra bloqueada",
+		langPortuguese: "O IP não está bloqueado",
+		langEnglish:    "The IP address is not blocked",
+	},
+	CodeBatchTooLarge: {
+		langSpanish:    "Se supero la cantidad maxima de IPs por consulta",
+		langPortuguese: "A quantidade máxima de IPs por consulta foi excedida",
+		langEnglish:    "The maximum number of IP addresses per request was exceeded",
+	},
+	CodeUnsupportedFormat: {
+		langSpanish:    "El formato de respuesta no está soportado",
+		langPortuguese: "O formato de resposta não é suportado",
+		langEnglish:    "The response format is not supported",
+	},
+	CodeClientIPUnknown: {
+		langSpanish:    "No fue posible determinar la IP del cliente",
+		langPortuguese: "Não foi possível determinar o IP do cliente",
+		langEnglish:    "The client IP address could not be determined",
+	},
+	CodeCountryNotOperated: {
+		langSpanish:    "El país de la IP no opera con MercadoLibre",
+		langPortuguese: "O país do IP não opera com o MercadoLivre",
+		langEnglish:    "MercadoLibre does not operate in the country of the IP address",
+	},
+	CodeCountryNotFound: {
+		langSpanish:    "El país no existe en MercadoLibre",
+		langPortuguese: "O país não existe no MercadoLivre",
+		langEnglish:    "The country does not exist in MercadoLibre",
+	},
+	CodeLocationNotFound: {
+		langSpanish:    "La ubicación no existe en MercadoLibre",
+		langPortuguese: "A localização não existe no MercadoLivre",
+		langEnglish:    "The location does not exist in MercadoLibre",
+	},
+	CodeSpecialPurposeIP: {
+		langSpanish:    "La IP pertenece a un rango de propósito especial",
+		langPortuguese: "O IP pertence a um intervalo de uso especial",
+		langEnglish:    "The IP address belongs to a special-purpose range",
+	},
+	CodeUnknownCurrency: {
+		langSpanish:    "La moneda no existe en MercadoLibre",
+		langPortuguese: "A moeda não existe no MercadoLivre",
+		langEnglish:    "The currency does not exist in MercadoLibre",
+	},
+	CodeGeolocationNotFound: {
+		langSpanish:    "No se encontró la geolocalización de la IP",
+		langPortuguese: "A geolocalização do IP não foi encontrada",
+		langEnglish:    "No geolocation was found for the IP address",
+	},
+	CodeGeolocationFailed: {
+		langSpanish:    "Falló la geolocalización de la IP",
+		langPortuguese: "Falha na geolocalização do IP",
+		langEnglish:    "The IP address geolocation failed",
+	},
+	CodeUpstreamUnavailable: {
+		langSpanish:    "La API de MercadoLibre no está disponible",
+		langPortuguese: "A API do MercadoLivre não está disponível",
+		langEnglish:    "The MercadoLibre API is unavailable",
+	},
+	CodeUpstreamTimeout: {
+		langSpanish:    "La API de MercadoLibre no respondió a tiempo",
+		langPortuguese: "A API do MercadoLivre não respondeu a tempo",
+		langEnglish:    "The MercadoLibre API timed out",
+	},
+	CodeUpstreamRateLimited: {
+		langSpanish:    "La API de MercadoLibre limitó las solicitudes",
+		langPortuguese: "A API do MercadoLivre limitou as requisições",
+		langEnglish:    "The MercadoLibre API rate limited the requests",
+	},
+	CodeUpstreamBadResponse: {
+		langSpanish:    "La API de MercadoLibre respondió con un error",
+		langPortuguese: "A API do MercadoLivre respondeu com um erro",
+		langEnglish:    "The MercadoLibre API returned an invalid response",
+	},
+	CodeUpstreamNotFound: {
+		langSpanish:    "La API de MercadoLibre no encontró el recurso",
+		langPortuguese: "A API do MercadoLivre não encontrou o recurso",
+		langEnglish:    "The MercadoLibre API did not find the resource",
+	},
+	CodeUpstreamUnauthorized: {
+		langSpanish:    "La API de MercadoLibre rechazó las credenciales",
+		langPortuguese: "A API do MercadoLivre rejeitou as credenciais",
+		langEnglish:    "The MercadoLibre API rejected the credentials",
+	},
+	CodePersistenceFailed: {
+		langSpanish:    "Error al guardar la lista de IPs bloqueadas",
+		langPortuguese: "Erro ao salvar a lista de IPs bloqueados",
+		langEnglish:    "The blocked IP list could not be saved",
+	},
+	CodeInternalError: {
+		langSpanish:    "Error interno del servidor",
+		langPortuguese: "Erro interno do servidor",
+		langEnglish:    "Internal server error",
+	},
+}
+
+// message retorna el mensaje de key en el idioma de la solicitud.
+func message(c *gin.Context, key string, args ...interface{}) string {
+	return translate(requestLanguage(c, c.GetString(countryLocaleKey)), key, args...)
+}
+
+// translate retorna el mensaje de key en lang, o en el idioma por defecto si no esta traducido.
+func translate(lang, key string, args ...interface{}) string {
+	text, ok := messages[key][lang]
+	if !ok {
+		if text, ok = messages[key][langSpanish]; !ok {
+			text = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// requestLanguage elige el idioma de los mensajes: el de Accept-Language si incluye alguno
+// soportado, si no el del Locale del país consultado (si se conoce) y si no español.
+func requestLanguage(c *gin.Context, locale string) string {
+	if accept := c.GetHeader("Accept-Language"); accept != "" {
+		if tags, _, err := language.ParseAcceptLanguage(accept); err == nil {
+			if lang, ok := matchLanguage(tags...); ok {
+				return lang
+			}
+		}
+	}
+	if locale != "" {
+		// MELI usa "_" como separador ("pt_BR")
+		if tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-")); err == nil {
+			if lang, ok := matchLanguage(tag); ok {
+				return lang
+			}
+		}
+	}
+	return langSpanish
+}
+
+// matchLanguage retorna el idioma soportado que mejor coincide con tags.
+func matchLanguage(tags ...language.Tag) (string, bool) {
+	if len(tags) == 0 {
+		return "", false
+	}
+	_, index, confidence := languageMatcher.Match(tags...)
+	if confidence == language.No {
+		return "", false
+	}
+	return supportedLanguages[index], true
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestContext crea un contexto de gin para una solicitud con el Accept-Language indicado.
+func newTestContext(acceptLanguage string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/ip/1.2.3.4", nil)
+	if acceptLanguage != "" {
+		c.Request.Header.Set("Accept-Language", acceptLanguage)
+	}
+	return c
+}
+
+// TestRequestLanguage verifica la eleccion del idioma: Accept-Language, luego el locale del país
+// consultado y por ultimo español.
+func TestRequestLanguage(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		locale         string
+		want           string
+	}{
+		{"pt-BR,pt;q=0.9", "", langPortuguese},
+		{"en-US", "pt_BR", langEnglish},
+		{"fr-FR, en;q=0.5", "", langEnglish},
+		{"es-AR;q=0.4, pt;q=0.8", "", langPortuguese},
+		{"", "pt_BR", langPortuguese},
+		{"", "es_AR", langSpanish},
+		{"fr-FR", "pt_BR", langPortuguese}, // sin idioma soportado en Accept-Language se usa el del país
+		{"de", "", langSpanish},
+		{"", "", langSpanish},
+		{";;invalid", "en_US", langEnglish},
+		{"", "xx", langSpanish},
+	}
+	for _, tt := range tests {
+		if got := requestLanguage(newTestContext(tt.acceptLanguage), tt.locale); got != tt.want {
+			t.Errorf("requestLanguage(%q, %q) = %q, se esperaba %q", tt.acceptLanguage, tt.locale, got, tt.want)
+		}
+	}
+}
+
+// TestMessageFallback verifica que un mensaje sin traducir se responde en español y que una
+// clave desconocida se responde tal cual.
+func TestMessageFallback(t *testing.T) {
+	if got := translate(langEnglish, CodeIPBlocked); got != messages[CodeIPBlocked][langEnglish] {
+		t.Errorf("translate(en) = %q", got)
+	}
+	if got := translate("fr", CodeIPBlocked); got != messages[CodeIPBlocked][langSpanish] {
+		t.Errorf("translate(fr) = %q, se esperaba el mensaje en español", got)
+	}
+	if got := translate(langEnglish, "unknown_key"); got != "unknown_key" {
+		t.Errorf("translate(clave desconocida) = %q", got)
+	}
+	// Sin Accept-Language, los mensajes de una consulta usan el idioma del país consultado
+	c := newTestContext("")
+	c.Set(countryLocaleKey, "pt_BR")
+	if got := message(c, CodeIPBlocked); got != messages[CodeIPBlocked][langPortuguese] {
+		t.Errorf("message() = %q, se esperaba el mensaje en portugués", got)
+	}
+}
+
+// TestLocalizeWarnings verifica que los avisos se traducen segun su fuente, con el codigo de error
+// y el texto original en debug, sin modificar la informacion original.
+func TestLocalizeWarnings(t *testing.T) {
+	info := &models.CountryInfo{Country: models.Country{ID: "BR", Locale: "pt_BR"}}
+	info.AddWarning("currency_conversion", fmt.Errorf("currency_conversion: %w", api.ErrTimeout))
+	info.AddWarning("conversions", errors.New("moneda del país desconocida"))
+	info.AddWarning("region", fmt.Errorf("region: %w", api.ErrUnavailable))
+
+	localized := localizeWarnings(langEnglish, info)
+	want := []models.Warning{
+		{Source: "currency_conversion", Code: CodeUpstreamTimeout, Error: messages[warningPrefix+"currency_conversion"][langEnglish]},
+		{Source: "conversions", Code: CodeInternalError, Error: messages[warningPrefix+"conversions"][langEnglish]},
+		// Sin mensaje para la fuente se usa el de su codigo
+		{Source: "region", Code: CodeUpstreamUnavailable, Error: messages[CodeUpstreamUnavailable][langEnglish]},
+	}
+	for i, warning := range localized.Warnings {
+		if warning.Source != want[i].Source || warning.Code != want[i].Code || warning.Error != want[i].Error {
+			t.Errorf("aviso %d = %+v, se esperaba %+v", i, warning, want[i])
+		}
+		if warning.Debug != info.Warnings[i].Err.Error() {
+			t.Errorf("aviso %d: debug = %q, se esperaba el error original", i, warning.Debug)
+		}
+	}
+	if info.Warnings[1].Error != "moneda del país desconocida" || info.Warnings[1].Code != "" {
+		t.Errorf("se modifico la informacion original: %+v", info.Warnings[1])
+	}
+
+	// Aplicarla de nuevo no cambia los avisos ya traducidos
+	again := localizeWarnings(langEnglish, localized)
+	for i := range again.Warnings {
+		if again.Warnings[i].Error != localized.Warnings[i].Error || again.Warnings[i].Debug != localized.Warnings[i].Debug {
+			t.Errorf("aviso %d cambio al traducirlo dos veces: %+v", i, again.Warnings[i])
+		}
+	}
+
+	// En un lote se usa el idioma del país de la IP si no hay Accept-Language
+	result := localizeResult(newTestContext(""), models.LookupResult{IP: "1.2.3.4", Status: models.LookupPartial, Data: info})
+	if got := result.Data.Warnings[0].Error; got != messages[warningPrefix+"currency_conversion"][langPortuguese] {
+		t.Errorf("aviso del lote = %q, se esperaba el mensaje en portugués", got)
+	}
+}
+
+// TestNotAcceptableDetails verifica que la lista de formatos soportados de un 406 se traduce
+// segun Accept-Language.
+func TestNotAcceptableDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/ip/block", func(c *gin.Context) {
+		if _, ok := negotiateFormat(c); ok {
+			c.Status(http.StatusOK)
+		}
+	})
+
+	for _, lang := range supportedLanguages {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/ip/block?format=yaml", nil)
+		r.Header.Set("Accept-Language", lang)
+		router.ServeHTTP(w, r)
+
+		var response ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusNotAcceptable {
+			t.Fatalf("%s: status %d, %v", lang, w.Code, err)
+		}
+		want := translate(lang, msgSupportedFormats, "json, ndjson, csv, xml, protobuf")
+		if response.Details != want || response.Error != translate(lang, msgFormatUnsupported, "yaml") {
+			t.Errorf("%s: details = %q, error = %q, se esperaba %q", lang, response.Details, response.Error, want)
+		}
+	}
+}
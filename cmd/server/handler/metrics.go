@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsContentType es el tipo de contenido del formato de texto de Prometheus.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// breakerStates son los estados posibles de un circuit breaker, en el orden en que se exponen.
+var breakerStates = []models.BreakerState{models.BreakerClosed, models.BreakerOpen, models.BreakerHalfOpen}
+
+// GetMetrics devuelve, en el formato de texto de Prometheus, el estado del servicio: consultas,
+// circuit breakers, refresco de los datos de referencia de MELI y suscripciones a los eventos.
+func (h *Handler) GetMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		health := h.Service.Health()
+		stats := h.Service.Stats()
+
+		var m metricsWriter
+		m.family("meli_lookups_total", "counter", "Consultas de IPs resueltas.")
+		m.sample("meli_lookups_total", nil, float64(stats.TotalInvocations))
+
+		m.family("meli_health_degraded", "gauge", "1 si algun circuit breaker no esta cerrado o fallo el ultimo refresco de algun dato.")
+		m.sample("meli_health_degraded", nil, boolValue(health.Status == models.HealthDegraded))
+
+		m.family("meli_circuit_breaker_state", "gauge", "Estado del circuit breaker de cada operacion de MELI (1 = estado actual).")
+		for _, breaker := range health.Breakers {
+			for _, state := range breakerStates {
+				m.sample("meli_circuit_breaker_state", []string{"operation", breaker.Name, "state", string(state)}, boolValue(breaker.State == state))
+			}
+		}
+		m.family("meli_circuit_breaker_consecutive_failures", "gauge", "Fallas consecutivas de cada operacion de MELI.")
+		for _, breaker := range health.Breakers {
+			m.sample("meli_circuit_breaker_consecutive_failures", []string{"operation", breaker.Name}, float64(breaker.Failures))
+		}
+
+		m.family("meli_refresh_total", "counter", "Refrescos de cada dato de referencia de MELI por resultado.")
+		for _, refresh := range health.Refresh {
+			m.sample("meli_refresh_total", []string{"dataset", refresh.Name, "result", "success"}, float64(refresh.Refreshes))
+			m.sample("meli_refresh_total", []string{"dataset", refresh.Name, "result", "failure"}, float64(refresh.Failures))
+		}
+		m.family("meli_refresh_consecutive_failures", "gauge", "Refrescos fallidos consecutivos de cada dato de referencia.")
+		for _, refresh := range health.Refresh {
+			m.sample("meli_refresh_consecutive_failures", []string{"dataset", refresh.Name}, float64(refresh.ConsecutiveFailures))
+		}
+		m.family("meli_refresh_last_success_timestamp_seconds", "gauge", "Momento del ultimo refresco exitoso de cada dato de referencia.")
+		for _, refresh := range health.Refresh {
+			if refresh.LastSuccess != nil {
+				m.sample("meli_refresh_last_success_timestamp_seconds", []string{"dataset", refresh.Name}, unixSeconds(*refresh.LastSuccess))
+			}
+		}
+		m.family("meli_refresh_expires_timestamp_seconds", "gauge", "Vencimiento del ultimo valor obtenido de cada dato de referencia.")
+		for _, refresh := range health.Refresh {
+			if refresh.ExpiresAt != nil {
+				m.sample("meli_refresh_expires_timestamp_seconds", []string{"dataset", refresh.Name}, unixSeconds(*refresh.ExpiresAt))
+			}
+		}
+
+		m.family("meli_event_subscribers", "gauge", "Clientes suscriptos a los eventos.")
+		m.sample("meli_event_subscribers", nil, float64(health.Events.Subscribers))
+		m.family("meli_event_subscribers_disconnected_total", "counter", "Suscriptores desconectados por no recibir los eventos a tiempo.")
+		m.sample("meli_event_subscribers_disconnected_total", nil, float64(health.Events.Disconnected))
+		m.family("meli_events_dropped_total", "counter", "Eventos que no se entregaron a algun suscriptor.")
+		m.sample("meli_events_dropped_total", nil, float64(health.Events.Dropped))
+
+		c.Data(http.StatusOK, metricsContentType, []byte(m.String()))
+	}
+}
+
+// metricsWriter arma una respuesta en el formato de texto de Prometheus.
+type metricsWriter struct {
+	strings.Builder
+}
+
+// family escribe la ayuda y el tipo de una metrica.
+func (m *metricsWriter) family(name, kind, help string) {
+	fmt.Fprintf(m, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample escribe un valor de una metrica. labels alterna nombres y valores.
+func (m *metricsWriter) sample(name string, labels []string, value float64) {
+	m.WriteString(name)
+	if len(labels) > 0 {
+		m.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				m.WriteByte(',')
+			}
+			fmt.Fprintf(m, "%s=\"%s\"", labels[i], labelReplacer.Replace(labels[i+1]))
+		}
+		m.WriteByte('}')
+	}
+	m.WriteByte(' ')
+	m.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	m.WriteByte('\n')
+}
+
+// labelReplacer escapa los valores de las etiquetas.
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"embed"
+	"github.com/gin-gonic/gin"
+	"io/fs"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenAPIDocument es la raiz de una especificacion OpenAPI 3.
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"` // path -> metodo en minusculas -> operacion
+	Components Components                       `json:"components"`
+}
+
+// OpenAPIInfo describe la API.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// Operation describe un metodo HTTP sobre un path.
+type Operation struct {
+	Summary     string              `json:"summary"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter describe un parametro de path, query o header.
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+// RequestBody describe el cuerpo de una solicitud.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describe una respuesta posible de una operacion.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType asocia un tipo de contenido con su esquema.
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// Components contiene los esquemas reutilizables.
+type Components struct {
+	Schemas map[string]Schema `json:"schemas"`
+}
+
+// Schema es un JSON Schema de OpenAPI.
+type Schema map[string]interface{}
+
+var (
+	openAPIOnce sync.Once
+	openAPISpec *OpenAPIDocument
+)
+
+// OpenAPISpec retorna la especificacion OpenAPI de todas las rutas del servidor.
+func OpenAPISpec() *OpenAPIDocument {
+	openAPIOnce.Do(func() {
+		openAPISpec = buildOpenAPISpec()
+	})
+	return openAPISpec
+}
+
+// GetOpenAPISpec sirve la especificacion OpenAPI en formato JSON.
+func (h *Handler) GetOpenAPISpec() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, OpenAPISpec())
+	}
+}
+
+// docsAssets contiene Swagger UI 5.18.2 (swagger-ui-bundle.js y swagger-ui.css), versionado en el
+// repositorio para que /docs no dependa de un CDN. Ver docs/README.md para actualizarlo.
+//
+//go:embed docs/swagger-ui-bundle.js docs/swagger-ui.css
+var docsAssets embed.FS
+
+// GetDocs sirve una pagina de documentacion (Swagger UI) que consume /openapi.json.
+func (h *Handler) GetDocs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+	}
+}
+
+// GetDocsAsset sirve los archivos de Swagger UI embebidos en el binario.
+func (h *Handler) GetDocsAsset() gin.HandlerFunc {
+	assets, _ := fs.Sub(docsAssets, "docs")
+	return func(c *gin.Context) {
+		file := c.Param("file")
+		if _, err := fs.Stat(assets, file); err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		// La version esta fijada en el binario, por lo que el contenido no cambia mientras corre
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.FileFromFS(file, http.FS(assets))
+	}
+}
+
+const docsPage = `<!DOCTYPE html>
+<html lang="es">
+<head>
+    <meta charset="UTF-8">
+    <title>Meli Challenge - API</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+    <style>body { margin: 0; padding: 0; }</style>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="/docs/assets/swagger-ui-bundle.js"></script>
+<script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui", deepLinking: true });
+</script>
+</body>
+</html>
+`
+
+////////////////////////////////
+// *** GENERACION DE ESQUEMAS ***
+
+// schemaRegistry genera esquemas a partir de tipos Go usando sus tags json.
+// Los structs con nombre se registran en components y se referencian con $ref.
+type schemaRegistry struct {
+	schemas map[string]Schema
+}
+
+// ref retorna el esquema de v, registrando sus structs en components.
+func (r *schemaRegistry) ref(v interface{}) Schema {
+	return r.schemaOf(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schemaOf(t reflect.Type) Schema {
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return Schema{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Ptr:
+		schema := r.schemaOf(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return Schema{"allOf": []Schema{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": r.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := r.schemas[name]; !ok {
+			r.schemas[name] = Schema{} // evita recursion infinita en tipos recursivos
+			r.schemas[name] = r.structSchema(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + name}
+	default:
+		// interface{}: cualquier valor JSON
+		return Schema{}
+	}
+}
+
+// schemaName es el nombre del esquema de un struct en components. Los DTOs de una version
+// de la API ("handler/v2") llevan la version como prefijo para no chocar con los de v1.
+func schemaName(t reflect.Type) string {
+	if version := path.Base(t.PkgPath()); len(version) > 1 && version[0] == 'v' && strings.Trim(version[1:], "0123456789") == "" {
+		return strings.ToUpper(version) + t.Name()
+	}
+	return t.Name()
+}
+
+// structSchema genera el esquema de un struct, aplanando los campos embebidos como hace encoding/json.
+func (r *schemaRegistry) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+	r.addFields(t, properties, &required)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (r *schemaRegistry) addFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			r.addFields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = r.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
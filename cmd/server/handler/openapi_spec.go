@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"github.com/AleHts29/meli-challenge/cmd/server/handler/v2"
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Parametros reutilizados por varias operaciones.
+var (
+	ipPathParam     = Parameter{Name: "ip", In: "path", Required: true, Description: "Dirección IPv4 o IPv6", Schema: Schema{"type": "string"}}
+	idPathParam     = Parameter{Name: "id", In: "path", Required: true, Description: "Identificador de MELI", Schema: Schema{"type": "string"}}
+	strictParam     = Parameter{Name: "strict", In: "query", Description: "Falla si alguna API de MELI falla, en lugar de devolver una respuesta parcial", Schema: Schema{"type": "boolean"}}
+	streamParam     = Parameter{Name: "stream", In: "query", Description: "Responde en NDJSON a medida que se resuelven las IPs (equivale a Accept: application/x-ndjson)", Schema: Schema{"type": "boolean"}}
+	toCurrencyParam = Parameter{Name: "to", In: "query", Description: "Monedas destino separadas por coma (ej: EUR,BRL)", Schema: Schema{"type": "string"}}
+	formatParam     = Parameter{Name: "format", In: "query", Description: "Formato de la respuesta; tiene prioridad sobre Accept", Schema: Schema{"type": "string", "enum": responseFormats}}
+	amountParam     = Parameter{Name: "amount", In: "query", Description: "Monto a convertir (por defecto 1)", Schema: Schema{"type": "number"}}
+)
+
+// buildOpenAPISpec arma la especificacion de todas las rutas registradas en el router.
+func buildOpenAPISpec() *OpenAPIDocument {
+	r := &schemaRegistry{schemas: map[string]Schema{}}
+
+	errorSchema := r.ref(ErrorResponse{})
+	r.schemas["ErrorResponse"]["properties"].(Schema)["code"] = Schema{"type": "string", "enum": errorCodes}
+
+	jsonContent := func(schema Schema) map[string]MediaType {
+		return map[string]MediaType{"application/json": {Schema: schema}}
+	}
+	ok := func(description string, schema Schema) map[string]Response {
+		return map[string]Response{"200": {Description: description, Content: jsonContent(schema)}}
+	}
+	// withErrors agrega a las respuestas los status de error que puede devolver la operacion.
+	withErrors := func(responses map[string]Response, statuses ...int) map[string]Response {
+		for _, status := range statuses {
+			responses[strconv.Itoa(status)] = Response{Description: http.StatusText(status), Content: jsonContent(errorSchema)}
+		}
+		return responses
+	}
+	// withFormats agrega a la respuesta 200 los formatos alternativos al JSON. records describe
+	// el registro plano que se usa en CSV, XML y protobuf (mensajes definidos en /static/records.proto).
+	withFormats := func(responses map[string]Response, records recordSet, ndjson Schema) map[string]Response {
+		response := responses["200"]
+		response.Content["application/x-ndjson"] = MediaType{Schema: ndjson}
+		response.Content["text/csv"] = MediaType{Schema: Schema{"type": "string", "description": "Columnas: " + strings.Join(records.columns, ", ")}}
+		response.Content["application/xml"] = MediaType{Schema: Schema{"type": "string", "description": "Elementos <" + records.element + ">, dentro de <" + records.list + "> en las listas"}}
+		response.Content["application/x-protobuf"] = MediaType{Schema: Schema{"type": "string", "format": "binary", "description": "Mensajes de /static/records.proto; las listas van delimitadas por longitud (varint)"}}
+		responses["200"] = response
+		return withErrors(responses, http.StatusNotAcceptable)
+	}
+	lookupErrors := []int{http.StatusBadRequest, http.StatusForbidden, http.StatusUnprocessableEntity, http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout}
+	upstreamErrors := []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout}
+
+	paths := map[string]map[string]*Operation{
+		"/": {
+			"get": {
+				Summary:   "Página de eventos en el navegador",
+				Tags:      []string{"docs"},
+				Responses: map[string]Response{"200": {Description: "HTML", Content: map[string]MediaType{"text/html": {Schema: Schema{"type": "string"}}}}},
+			},
+		},
+		"/openapi.json": {
+			"get": {
+				Summary:   "Especificación OpenAPI 3 de la API",
+				Tags:      []string{"docs"},
+				Responses: ok("Especificación", Schema{"type": "object"}),
+			},
+		},
+		"/docs": {
+			"get": {
+				Summary:   "Documentación navegable de la API (Swagger UI)",
+				Tags:      []string{"docs"},
+				Responses: map[string]Response{"200": {Description: "HTML", Content: map[string]MediaType{"text/html": {Schema: Schema{"type": "string"}}}}},
+			},
+		},
+		"/docs/assets/{file}": {
+			"get": {
+				Summary:    "Archivos de Swagger UI embebidos en el binario",
+				Tags:       []string{"docs"},
+				Parameters: []Parameter{{Name: "file", In: "path", Required: true, Description: "swagger-ui-bundle.js o swagger-ui.css", Schema: Schema{"type": "string"}}},
+				Responses: map[string]Response{
+					"200": {Description: "Archivo", Content: map[string]MediaType{"application/javascript": {Schema: Schema{"type": "string"}}, "text/css": {Schema: Schema{"type": "string"}}}},
+					"404": {Description: "El archivo no existe"},
+				},
+			},
+		},
+		"/health": {
+			"get": {
+				Summary:   "Estado del servicio y de los circuit breakers de las APIs de MELI",
+				Tags:      []string{"health"},
+				Responses: ok("Estado (degraded si algún breaker no está cerrado)", r.ref(models.Health{})),
+			},
+		},
+		"/metrics": {
+			"get": {
+				Summary:   "Métricas de consultas, circuit breakers y refresco de datos de MELI",
+				Tags:      []string{"health"},
+				Responses: map[string]Response{"200": {Description: "Formato de texto de Prometheus", Content: map[string]MediaType{"text/plain": {Schema: Schema{"type": "string"}}}}},
+			},
+		},
+		"/api/ip/me": {
+			"get": {
+				Summary:    "Información del país desde el que se origina la solicitud",
+				Tags:       []string{"ip"},
+				Parameters: []Parameter{formatParam, strictParam},
+				Responses:  withFormats(withErrors(ok("País del cliente", r.ref(CallerCountryResponse{})), lookupErrors...), lookupRecords, r.ref(CallerCountryResponse{})),
+			},
+		},
+		"/api/ip/{ip}": {
+			"get": {
+				Summary:    "Información del país asociado a una IP",
+				Tags:       []string{"ip"},
+				Parameters: []Parameter{formatParam, ipPathParam, strictParam, toCurrencyParam, amountParam},
+				Responses:  withFormats(withErrors(ok("Información del país", r.ref(models.CountryInfo{})), lookupErrors...), lookupRecords, r.ref(models.CountryInfo{})),
+			},
+		},
+		"/api/ip/lookup": {
+			"post": {
+				Summary:     "Información de países para un lote de IPs",
+				Tags:        []string{"ip"},
+				Parameters:  []Parameter{formatParam, strictParam, streamParam},
+				RequestBody: &RequestBody{Required: true, Content: jsonContent(r.ref(LookupRequest{}))},
+				Responses: withErrors(withFormats(map[string]Response{"200": {
+					Description: "Resultados agrupados por estado en JSON; en los demás formatos, un resultado por registro a medida que se resuelven",
+					Content:     jsonContent(r.ref(models.BatchLookupResponse{})),
+				}}, lookupRecords, r.ref(models.LookupResult{})), http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+			},
+		},
+		"/api/ip/block": {
+			"get": {
+				Summary:    "Lista los bloqueos vigentes",
+				Tags:       []string{"block"},
+				Parameters: []Parameter{formatParam},
+				Responses:  withFormats(ok("Bloqueos vigentes", r.ref(BlockListResponse{})), blockRecords, r.ref(ipinfo.BlockedIP{})),
+			},
+			"post": {
+				Summary:     "Bloquea una o varias IPs",
+				Tags:        []string{"block"},
+				RequestBody: &RequestBody{Required: true, Content: jsonContent(r.ref(BlockRequest{}))},
+				Responses:   withErrors(ok("Bloqueo exitoso", r.ref(BlockResponse{})), http.StatusBadRequest, http.StatusInternalServerError),
+			},
+		},
+		"/api/ip/block/{ip}": {
+			"delete": {
+				Summary:    "Desbloquea una IP",
+				Tags:       []string{"block"},
+				Parameters: []Parameter{ipPathParam},
+				Responses:  withErrors(ok("Desbloqueo exitoso", r.ref(UnblockResponse{})), http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError),
+			},
+		},
+		"/api/ip/events": {
+			"get": {
+				Summary: "Stream de eventos (Server-Sent Events)",
+				Tags:    []string{"block"},
+				Responses: map[string]Response{"200": {
+					Description: "Cada mensaje `data:` contiene un evento en JSON",
+					Content:     map[string]MediaType{"text/event-stream": {Schema: r.ref(models.Event{})}},
+				}},
+			},
+		},
+		"/api/v2/ip/me": {
+			"get": {
+				Summary:    "Información de la IP que origina la solicitud (contrato v2)",
+				Tags:       []string{"ip v2"},
+				Parameters: []Parameter{formatParam, strictParam},
+				Responses:  withFormats(withErrors(ok("Información de la IP", r.ref(v2.IPLookup{})), lookupErrors...), lookupRecords, r.ref(v2.IPLookup{})),
+			},
+		},
+		"/api/v2/ip/{ip}": {
+			"get": {
+				Summary:    "Información de una IP (contrato v2)",
+				Tags:       []string{"ip v2"},
+				Parameters: []Parameter{formatParam, ipPathParam, strictParam, toCurrencyParam, amountParam},
+				Responses:  withFormats(withErrors(ok("Información de la IP", r.ref(v2.IPLookup{})), lookupErrors...), lookupRecords, r.ref(v2.IPLookup{})),
+			},
+		},
+		"/api/v2/ip/lookup": {
+			"post": {
+				Summary:     "Información de un lote de IPs (contrato v2)",
+				Tags:        []string{"ip v2"},
+				Parameters:  []Parameter{formatParam, strictParam, streamParam},
+				RequestBody: &RequestBody{Required: true, Content: jsonContent(r.ref(LookupRequest{}))},
+				Responses: withErrors(withFormats(map[string]Response{"200": {
+					Description: "Resultados con un resumen por estado en JSON; en los demás formatos, un resultado por registro a medida que se resuelven",
+					Content:     jsonContent(r.ref(v2.BatchLookupResponse{})),
+				}}, lookupRecords, r.ref(v2.LookupResult{})), http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+			},
+		},
+		"/api/countries": {
+			"get": {
+				Summary:   "Países en los que opera MELI",
+				Tags:      []string{"reference"},
+				Responses: withErrors(ok("Países", r.ref([]models.Country{})), upstreamErrors...),
+			},
+		},
+		"/api/countries/{id}": {
+			"get": {
+				Summary:    "Detalle de un país con sus estados",
+				Tags:       []string{"reference"},
+				Parameters: []Parameter{idPathParam},
+				Responses:  withErrors(ok("País", r.ref(models.CountryInfo{})), append([]int{http.StatusNotFound}, upstreamErrors...)...),
+			},
+		},
+		"/api/states/{id}": {
+			"get": {
+				Summary:    "Detalle de un estado con sus ciudades",
+				Tags:       []string{"reference"},
+				Parameters: []Parameter{idPathParam},
+				Responses:  withErrors(ok("Estado", r.ref(models.StateInfo{})), append([]int{http.StatusNotFound}, upstreamErrors...)...),
+			},
+		},
+		"/api/cities/{id}": {
+			"get": {
+				Summary:    "Detalle de una ciudad con sus barrios",
+				Tags:       []string{"reference"},
+				Parameters: []Parameter{idPathParam},
+				Responses:  withErrors(ok("Ciudad", r.ref(models.CityInfo{})), append([]int{http.StatusNotFound}, upstreamErrors...)...),
+			},
+		},
+		"/api/currencies": {
+			"get": {
+				Summary:   "Monedas de MELI",
+				Tags:      []string{"reference"},
+				Responses: withErrors(ok("Monedas", r.ref([]models.Currency{})), upstreamErrors...),
+			},
+		},
+		"/api/currencies/{id}/rate": {
+			"get": {
+				Summary: "Cotización de una moneda",
+				Tags:    []string{"reference"},
+				Parameters: []Parameter{idPathParam, {
+					Name: "to", In: "query", Description: "Moneda destino (por defecto USD)", Schema: Schema{"type": "string"},
+				}},
+				Responses: withErrors(ok("Cotización", r.ref(models.CurrencyExchange{})), append([]int{http.StatusBadRequest}, upstreamErrors...)...),
+			},
+		},
+		"/api/stats": {
+			"get": {
+				Summary:   "Consultas por país y distancias al punto de referencia",
+				Tags:      []string{"stats"},
+				Responses: ok("Estadísticas", r.ref(models.UsageStats{})),
+			},
+		},
+	}
+
+	// El payload de los eventos depende de su tipo
+	r.schemas["Event"]["properties"].(Schema)["payload"] = Schema{"oneOf": []Schema{
+		r.ref(models.BlockedPayload{}), r.ref(models.UnblockedPayload{}), r.ref(models.ExpiredPayload{}),
+		r.ref(models.LookupDeniedPayload{}), r.ref(models.StateReloadedPayload{}), r.ref(models.UpstreamDegradedPayload{}),
+		r.ref(models.SubscriptionClosedPayload{}),
+	}}
+	r.schemas["Event"]["properties"].(Schema)["event"] = Schema{"type": "string", "enum": []models.EventType{
+		models.EventBlocked, models.EventUnblocked, models.EventExpired,
+		models.EventLookupDenied, models.EventStateReloaded, models.EventUpstreamDegraded, models.EventSubscriptionClosed,
+	}}
+
+	return &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       "Meli Challenge API",
+			Description: "Información contextual de direcciones IP, bloqueos y notificaciones. Los mensajes de error se traducen según Accept-Language (es, pt, en); el campo code no depende del idioma.",
+			Version:     "1.0.0",
+		},
+		Paths:      paths,
+		Components: Components{Schemas: r.schemas},
+	}
+}
+
+// OpenAPIPath convierte un path de gin ("/api/ip/:ip") al formato de OpenAPI ("/api/ip/{ip}").
+func OpenAPIPath(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"strings"
+	"time"
+)
+
+// lookupRecords es la representacion plana de la consulta de una IP (mensaje LookupRecord).
+var lookupRecords = recordSet{
+	list:    "lookups",
+	element: "lookup",
+	columns: []string{
+		"ip", "status", "code", "error", "country_code", "country_name", "region", "city",
+		"meli_country_id", "meli_country_name", "currency_id", "usd_rate", "usd_rate_valid_until",
+		"time_zone", "local_time", "distance_km", "state_id", "state_name", "partial", "warnings",
+		"stale", "stale_sources",
+	},
+}
+
+// blockRecords es la representacion plana de un bloqueo (mensaje BlockRecord).
+var blockRecords = recordSet{
+	list:    "blocked_ips",
+	element: "blocked_ip",
+	columns: []string{"ip", "blocked_at", "expires_at"},
+}
+
+// lookupItem arma el elemento de respuesta de un resultado; body es lo que se escribe en JSON y NDJSON.
+func lookupItem(result models.LookupResult, body interface{}) renderable {
+	values := []interface{}{result.IP, string(result.Status), result.Code, result.Error}
+
+	info := result.Data
+	if info == nil {
+		info = &models.CountryInfo{}
+	}
+	ipInfo := info.IPInfo
+	if ipInfo == nil {
+		ipInfo = &models.IPInfo{}
+	}
+	var rate *float64
+	var validUntil string
+	if conversion := info.CurrencyConversionToUSD; conversion != nil {
+		rate, validUntil = &conversion.Rate, conversion.ValidUntil
+	}
+	var localTime string
+	if info.Localized != nil {
+		localTime = info.Localized.LocalTime
+	}
+	var stateID, stateName string
+	if info.Region != nil {
+		stateID, stateName = info.Region.StateID, info.Region.StateName
+	}
+	warnings := make([]string, len(info.Warnings))
+	for i, warning := range info.Warnings {
+		warnings[i] = warning.Source + ": " + warning.Error
+	}
+
+	values = append(values,
+		ipInfo.CountryCode, ipInfo.CountryName, ipInfo.Region, ipInfo.City,
+		info.ID, info.Name, info.CurrencyId, rate, validUntil,
+		info.TimeZone, localTime, info.DistanceKm, stateID, stateName, info.Partial, strings.Join(warnings, "; "),
+		info.Stale, strings.Join(info.StaleSources, "; "),
+	)
+	return renderable{body: body, values: values}
+}
+
+// countryItem arma el elemento de respuesta de la consulta de una sola IP.
+func countryItem(ip string, countryInfo *models.CountryInfo, body interface{}) renderable {
+	status := models.LookupOK
+	if countryInfo.Partial {
+		status = models.LookupPartial
+	}
+	return lookupItem(models.LookupResult{IP: ip, Status: status, Data: countryInfo}, body)
+}
+
+// blockItem arma el elemento de respuesta de un bloqueo.
+func blockItem(entry ipinfo.BlockedIP) renderable {
+	var expiresAt string
+	if entry.ExpiresAt != nil {
+		expiresAt = entry.ExpiresAt.Format(time.RFC3339)
+	}
+	return renderable{body: entry, values: []interface{}{entry.IP, entry.BlockedAt.Format(time.RFC3339), expiresAt}}
+}
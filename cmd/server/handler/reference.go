@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// GetCountries devuelve la lista de países en los que opera MELI.
+func (h *Handler) GetCountries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		countries, err := h.Service.Countries(c.Request.Context())
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, countries)
+	}
+}
+
+// GetCountry devuelve el detalle de un país, incluyendo sus estados.
+func (h *Handler) GetCountry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		country, err := h.Service.Country(c.Request.Context(), strings.ToUpper(c.Param("id")))
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		markStale(c, country)
+		c.JSON(http.StatusOK, country)
+	}
+}
+
+// GetCurrencies devuelve la lista de monedas de MELI.
+func (h *Handler) GetCurrencies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currencies, err := h.Service.Currencies(c.Request.Context())
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, currencies)
+	}
+}
+
+// GetCurrencyRate devuelve la cotización de una moneda a otra (?to=, por defecto USD).
+func (h *Handler) GetCurrencyRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from := strings.ToUpper(c.Param("id"))
+		to := strings.ToUpper(c.DefaultQuery("to", ipinfo.BaseCurrency))
+
+		rate, err := h.Service.CurrencyRate(c.Request.Context(), from, to)
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rate)
+	}
+}
+
+// GetState devuelve el detalle de un estado de MELI con sus ciudades.
+func (h *Handler) GetState() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state, err := h.Service.State(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+// GetCity devuelve el detalle de una ciudad de MELI con sus barrios.
+func (h *Handler) GetCity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		city, err := h.Service.City(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, city)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// referenceService es un ipinfo.Service que solo conoce el país AR, el estado AR-C, la ciudad
+// Palermo y las monedas ARS y USD. Los demas metodos no se usan.
+type referenceService struct {
+	ipinfo.Service
+}
+
+func (s *referenceService) Country(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	if countryID != "AR" {
+		return nil, fmt.Errorf("'%s': %w", countryID, ipinfo.ErrCountryNotFound)
+	}
+	return &models.CountryInfo{Country: models.Country{ID: "AR", Name: "Argentina"}, States: []models.State{{ID: "AR-C", Name: "Capital Federal"}}}, nil
+}
+
+func (s *referenceService) State(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	if stateID != "AR-C" {
+		return nil, fmt.Errorf("estado '%s': %w", stateID, ipinfo.ErrLocationNotFound)
+	}
+	return &models.StateInfo{ID: stateID, Name: "Capital Federal"}, nil
+}
+
+func (s *referenceService) City(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	if cityID != "TUxBQ1BBTDI1MTVh" {
+		return nil, fmt.Errorf("ciudad '%s': %w", cityID, ipinfo.ErrLocationNotFound)
+	}
+	return &models.CityInfo{ID: cityID, Name: "Palermo"}, nil
+}
+
+func (s *referenceService) CurrencyRate(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	for _, currency := range []string{from, to} {
+		if currency != "ARS" && currency != "USD" {
+			return nil, fmt.Errorf("'%s': %w", currency, ipinfo.ErrUnknownCurrency)
+		}
+	}
+	return &models.CurrencyExchange{CurrencyBase: from, CurrencyQuote: to, Rate: 0.001}, nil
+}
+
+// getReference envia una solicitud a los endpoints de datos de referencia.
+func getReference(h *Handler, target string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/api/countries/:id", h.GetCountry())
+	router.GET("/api/currencies/:id/rate", h.GetCurrencyRate())
+	router.GET("/api/states/:id", h.GetState())
+	router.GET("/api/cities/:id", h.GetCity())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// TestReferenceEndpoints verifica las respuestas de los endpoints de países, estados, ciudades y
+// cotizaciones, incluido el 404 de un país o una ubicacion desconocidos.
+func TestReferenceEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(&referenceService{}, &config.Config{})
+
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantCode   string
+	}{
+		// el ID se pasa en mayusculas
+		{"/api/countries/ar", http.StatusOK, ""},
+		{"/api/countries/XX", http.StatusNotFound, CodeCountryNotFound},
+		// sin ?to= se cotiza a USD
+		{"/api/currencies/ars/rate", http.StatusOK, ""},
+		{"/api/currencies/ARS/rate?to=XXX", http.StatusBadRequest, CodeUnknownCurrency},
+		{"/api/currencies/XXX/rate", http.StatusBadRequest, CodeUnknownCurrency},
+		{"/api/states/AR-C", http.StatusOK, ""},
+		{"/api/states/AR-XX", http.StatusNotFound, CodeLocationNotFound},
+		{"/api/cities/TUxBQ1BBTDI1MTVh", http.StatusOK, ""},
+		{"/api/cities/XX", http.StatusNotFound, CodeLocationNotFound},
+	}
+	for _, tt := range tests {
+		w := getReference(h, tt.target)
+		var response ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: respuesta invalida: %v", tt.target, err)
+		}
+		if w.Code != tt.wantStatus || response.Code != tt.wantCode {
+			t.Errorf("%s: se obtuvo %d %s; se esperaba %d %q", tt.target, w.Code, w.Body, tt.wantStatus, tt.wantCode)
+		}
+	}
+
+	var country models.CountryInfo
+	if err := json.Unmarshal(getReference(h, "/api/countries/ar").Body.Bytes(), &country); err != nil || len(country.States) != 1 {
+		t.Errorf("se obtuvo %+v; se esperaba el país con sus estados", country)
+	}
+}
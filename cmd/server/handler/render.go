@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protowire"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////
+// *** NEGOCIACION DE FORMATO ***
+
+// responseFormat es un formato de respuesta soportado.
+type responseFormat string
+
+const (
+	formatJSON     responseFormat = "json"
+	formatNDJSON   responseFormat = "ndjson"
+	formatCSV      responseFormat = "csv"
+	formatXML      responseFormat = "xml"
+	formatProtobuf responseFormat = "protobuf"
+)
+
+// responseFormats son los formatos que acepta ?format=, en orden de preferencia.
+var responseFormats = []responseFormat{formatJSON, formatNDJSON, formatCSV, formatXML, formatProtobuf}
+
+// contentTypes es el Content-Type de cada formato.
+var contentTypes = map[responseFormat]string{
+	formatJSON:     "application/json; charset=utf-8",
+	formatNDJSON:   "application/x-ndjson",
+	formatCSV:      "text/csv; charset=utf-8",
+	formatXML:      "application/xml; charset=utf-8",
+	formatProtobuf: "application/x-protobuf",
+}
+
+// mediaTypeFormats asocia los valores de Accept con el formato correspondiente. Los comodines
+// */* y application/* se responden en JSON (ver acceptedFormat).
+var mediaTypeFormats = map[string]responseFormat{
+	"application/json":                formatJSON,
+	"application/x-ndjson":            formatNDJSON,
+	"application/ndjson":              formatNDJSON,
+	"text/csv":                        formatCSV,
+	"application/xml":                 formatXML,
+	"text/xml":                        formatXML,
+	"application/x-protobuf":          formatProtobuf,
+	"application/protobuf":            formatProtobuf,
+	"application/vnd.google.protobuf": formatProtobuf,
+}
+
+// negotiateFormat elige el formato de la respuesta. ?format= tiene prioridad sobre Accept y
+// ?stream=true equivale a ?format=ndjson. Sin Accept se responde en JSON. Si el formato
+// pedido no esta soportado escribe la respuesta de error y retorna false.
+func negotiateFormat(c *gin.Context) (responseFormat, bool) {
+	if value := strings.ToLower(c.Query("format")); value != "" {
+		for _, format := range responseFormats {
+			if value == string(format) {
+				return format, true
+			}
+		}
+		respondError(c, http.StatusNotAcceptable, CodeUnsupportedFormat, message(c, msgFormatUnsupported, value), gin.H{"details": supportedFormats(c)})
+		return "", false
+	}
+
+	if c.Query("stream") == "true" {
+		return formatNDJSON, true
+	}
+
+	format, ok := acceptedFormat(c.GetHeader("Accept"))
+	if !ok {
+		respondError(c, http.StatusNotAcceptable, CodeUnsupportedFormat, message(c, msgAcceptUnsupported), gin.H{"details": supportedFormats(c)})
+		return "", false
+	}
+	return format, true
+}
+
+// acceptedFormat elige el formato de un header Accept: el tipo soportado con mayor q. Si el tipo
+// preferido por el cliente no esta soportado (como el text/html de un navegador) y acepta
+// cualquier tipo con */* o application/*, se responde en JSON. Ante igual q se prefiere JSON y
+// luego el orden de responseFormats.
+func acceptedFormat(accept string) (responseFormat, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+
+	var best responseFormat
+	bestQ, topQ, wildcardQ := 0.0, 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		// q=0 indica que el tipo no es aceptable
+		if q <= 0 {
+			continue
+		}
+		topQ = math.Max(topQ, q)
+
+		if mediaType == "*/*" || mediaType == "application/*" {
+			wildcardQ = math.Max(wildcardQ, q)
+			continue
+		}
+		format, ok := mediaTypeFormats[mediaType]
+		if !ok {
+			continue
+		}
+		if q > bestQ || (q == bestQ && formatRank(format) < formatRank(best)) {
+			best, bestQ = format, q
+		}
+	}
+
+	switch {
+	case best != "" && bestQ >= topQ:
+		return best, true
+	case wildcardQ > 0:
+		return formatJSON, true
+	default:
+		return best, best != ""
+	}
+}
+
+// formatRank es la posicion de format en responseFormats; los formatos desconocidos van al final.
+func formatRank(format responseFormat) int {
+	for i, candidate := range responseFormats {
+		if candidate == format {
+			return i
+		}
+	}
+	return len(responseFormats)
+}
+
+// supportedFormats describe, en el idioma de la solicitud, los formatos soportados para los
+// mensajes de error.
+func supportedFormats(c *gin.Context) string {
+	names := make([]string, len(responseFormats))
+	for i, format := range responseFormats {
+		names[i] = string(format)
+	}
+	return message(c, msgSupportedFormats, strings.Join(names, ", "))
+}
+
+////////////////////////////////
+// *** REGISTROS ***
+
+// recordSet describe la representacion plana de un recurso, compartida por CSV, XML y protobuf.
+// Cada columna es una columna CSV, un elemento XML y el campo protobuf con numero posicion+1
+// (ver static/records.proto).
+type recordSet struct {
+	list    string   // elemento raiz XML de una lista
+	element string   // elemento XML de cada registro
+	columns []string // nombres en snake_case
+}
+
+// renderable es un elemento de una respuesta: body se escribe en JSON y NDJSON y values,
+// en el orden de las columnas de su recordSet, en CSV, XML y protobuf. Los valores pueden ser
+// string, bool, int, float64 o *float64 (nil se omite).
+type renderable struct {
+	body   interface{}
+	values []interface{}
+}
+
+// renderOne responde un unico recurso en el formato negociado.
+func renderOne(c *gin.Context, format responseFormat, set recordSet, item renderable) {
+	if format == formatJSON {
+		c.JSON(http.StatusOK, item.body)
+		return
+	}
+	w := newResponseWriter(c, format, set, false)
+	w.write(item)
+	w.close()
+}
+
+////////////////////////////////
+// *** ESCRITURA ***
+
+// responseWriter escribe los elementos de una respuesta a medida que se generan. Si falla la
+// escritura (el cliente se desconecto) se descartan los elementos siguientes.
+type responseWriter struct {
+	c      *gin.Context
+	format responseFormat
+	set    recordSet
+	list   bool // lista de registros: raiz XML y mensajes protobuf delimitados
+	csv    *csv.Writer
+	xml    *xml.Encoder
+	failed bool
+}
+
+// newResponseWriter escribe el Content-Type, el status y el encabezado del formato (CSV o XML).
+// JSON no se escribe como stream: cada endpoint arma su propio cuerpo.
+func newResponseWriter(c *gin.Context, format responseFormat, set recordSet, list bool) *responseWriter {
+	c.Writer.Header().Set("Content-Type", contentTypes[format])
+	c.Status(http.StatusOK)
+
+	w := &responseWriter{c: c, format: format, set: set, list: list}
+	switch format {
+	case formatCSV:
+		w.csv = csv.NewWriter(c.Writer)
+		w.check(w.csv.Write(set.columns))
+	case formatXML:
+		w.xml = xml.NewEncoder(c.Writer)
+		w.check(w.xml.EncodeToken(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)}))
+		if list {
+			w.check(w.xml.EncodeToken(xml.StartElement{Name: xml.Name{Local: set.list}}))
+		}
+	}
+	return w
+}
+
+// write escribe un elemento y lo envia al cliente.
+func (w *responseWriter) write(item renderable) {
+	if w.failed {
+		return
+	}
+
+	switch w.format {
+	case formatNDJSON:
+		w.check(json.NewEncoder(w.c.Writer).Encode(item.body))
+	case formatCSV:
+		row := make([]string, len(item.values))
+		for i, value := range item.values {
+			row[i] = formatValue(value)
+		}
+		w.check(w.csv.Write(row))
+		w.csv.Flush()
+		w.check(w.csv.Error())
+	case formatXML:
+		w.check(w.writeXML(item.values))
+	case formatProtobuf:
+		w.check(w.writeProto(item.values))
+	}
+
+	if !w.failed {
+		w.c.Writer.Flush()
+	}
+}
+
+// close cierra la raiz XML y envia lo que quede pendiente.
+func (w *responseWriter) close() {
+	if w.failed {
+		return
+	}
+	if w.xml != nil {
+		if w.list {
+			w.check(w.xml.EncodeToken(xml.EndElement{Name: xml.Name{Local: w.set.list}}))
+		}
+		w.check(w.xml.Flush())
+	}
+	if w.csv != nil {
+		w.csv.Flush()
+	}
+	w.c.Writer.Flush()
+}
+
+// check registra el primer error de escritura.
+func (w *responseWriter) check(err error) {
+	if err != nil && !w.failed {
+		log.Printf("Error: al enviar respuesta en formato %s: %v", w.format, err)
+		w.failed = true
+	}
+}
+
+// writeXML escribe un registro como un elemento con un hijo por columna.
+func (w *responseWriter) writeXML(values []interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: w.set.element}}
+	if err := w.xml.EncodeToken(start); err != nil {
+		return err
+	}
+	for i, value := range values {
+		if isNil(value) {
+			continue
+		}
+		if err := w.xml.EncodeElement(formatValue(value), xml.StartElement{Name: xml.Name{Local: w.set.columns[i]}}); err != nil {
+			return err
+		}
+	}
+	if err := w.xml.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	return w.xml.Flush()
+}
+
+// writeProto escribe un registro como mensaje protobuf. En una lista cada mensaje va precedido
+// por su longitud (varint), como en writeDelimitedTo de las librerias de protobuf.
+func (w *responseWriter) writeProto(values []interface{}) error {
+	message := marshalRecord(values)
+	if w.list {
+		message = append(protowire.AppendVarint(nil, uint64(len(message))), message...)
+	}
+	_, err := w.c.Writer.Write(message)
+	return err
+}
+
+// marshalRecord codifica un registro como mensaje protobuf. Como en proto3 los valores
+// cero no se escriben, salvo los *float64 no nulos (campos optional).
+func marshalRecord(values []interface{}) []byte {
+	var b []byte
+	for i, value := range values {
+		number := protowire.Number(i + 1)
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				b = protowire.AppendTag(b, number, protowire.BytesType)
+				b = protowire.AppendString(b, v)
+			}
+		case bool:
+			if v {
+				b = protowire.AppendTag(b, number, protowire.VarintType)
+				b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+			}
+		case int:
+			if v != 0 {
+				b = protowire.AppendTag(b, number, protowire.VarintType)
+				b = protowire.AppendVarint(b, uint64(v))
+			}
+		case float64:
+			if v != 0 {
+				b = appendDouble(b, number, v)
+			}
+		case *float64:
+			if v != nil {
+				b = appendDouble(b, number, *v)
+			}
+		}
+	}
+	return b
+}
+
+func appendDouble(b []byte, number protowire.Number, v float64) []byte {
+	b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+// formatValue representa un valor como texto para CSV y XML. nil se representa vacio.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isNil(value interface{}) bool {
+	v, ok := value.(*float64)
+	return ok && v == nil
+}
@@ -0,0 +1,359 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/AleHts29/meli-challenge/internal/ipinfo"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protowire"
+	"io"
+	"math"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAcceptedFormat verifica la negociacion del formato con el header Accept.
+func TestAcceptedFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   responseFormat
+		ok     bool
+	}{
+		{"", formatJSON, true},
+		{"application/xml", formatXML, true},
+		{"text/csv, application/json;q=0.5", formatCSV, true},
+		{"application/json;q=0.5, application/x-protobuf", formatProtobuf, true},
+		// Navegador: text/html no esta soportado y */* se responde en JSON, aunque liste XML con mayor q
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8", formatJSON, true},
+		{"*/*", formatJSON, true},
+		{"application/*", formatJSON, true},
+		{"application/xml, */*", formatXML, true},
+		{"application/xml;q=0.5, */*", formatJSON, true},
+		// Ante igual q se prefiere JSON y luego el orden de responseFormats
+		{"application/xml, application/json", formatJSON, true},
+		{"application/x-protobuf, text/csv", formatCSV, true},
+		// Sin comodin se usa el tipo soportado con mayor q aunque no sea el preferido
+		{"text/html, application/xml;q=0.9", formatXML, true},
+		{"application/xml;q=0, */*;q=0.1", formatJSON, true},
+		{"text/html", "", false},
+		{"application/json;q=0", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := acceptedFormat(tt.accept); got != tt.want || ok != tt.ok {
+			t.Errorf("acceptedFormat(%q) = %q, %v; se esperaba %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// sampleLookup es un resultado con todas las columnas de lookupRecords completas.
+func sampleLookup(ip string) models.LookupResult {
+	distance := 1234.5
+	return models.LookupResult{
+		IP:     ip,
+		Status: models.LookupPartial,
+		Code:   "UPSTREAM_UNAVAILABLE",
+		Error:  "fallo una fuente",
+		Data: &models.CountryInfo{
+			Country:                 models.Country{ID: "AR", Name: "Argentina", CurrencyId: "ARS"},
+			TimeZone:                "America/Argentina/Buenos_Aires",
+			CurrencyConversionToUSD: &models.CurrencyExchange{Rate: 0.001, ValidUntil: "2024-01-01T00:00:00Z"},
+			Region:                  &models.RegionMatch{Region: "Cordoba", StateID: "AR-X", StateName: "Córdoba"},
+			DistanceKm:              &distance,
+			Localized:               &models.LocalizedInfo{LocalTime: "2024-01-01T09:00:00-03:00"},
+			IPInfo:                  &models.IPInfo{IP: ip, CountryCode: "AR", CountryName: "Argentina", Region: "Cordoba", City: "Cordoba"},
+			Partial:                 true,
+			Warnings:                []models.Warning{{Source: "currencies", Error: "timeout"}},
+			Stale:                   true,
+			StaleSources:            []string{"country"},
+		},
+	}
+}
+
+// protoField es un campo de un mensaje de static/records.proto.
+type protoField struct {
+	name     string
+	number   int
+	wireType protowire.Type
+}
+
+// protoWireTypes es el tipo de codificacion de cada tipo escalar usado en records.proto.
+var protoWireTypes = map[string]protowire.Type{
+	"string": protowire.BytesType,
+	"bool":   protowire.VarintType,
+	"int64":  protowire.VarintType,
+	"double": protowire.Fixed64Type,
+}
+
+// readProtoMessages lee los campos de cada mensaje de static/records.proto.
+func readProtoMessages(t *testing.T) map[string][]protoField {
+	t.Helper()
+	data, err := os.ReadFile("../../../static/records.proto")
+	if err != nil {
+		t.Fatalf("no se pudo leer records.proto: %v", err)
+	}
+
+	messageRe := regexp.MustCompile(`(?s)message (\w+) \{(.*?)\}`)
+	fieldRe := regexp.MustCompile(`(?m)^\s*(?:optional\s+)?(\w+)\s+(\w+)\s*=\s*(\d+);`)
+	messages := make(map[string][]protoField)
+	for _, message := range messageRe.FindAllStringSubmatch(string(data), -1) {
+		for _, field := range fieldRe.FindAllStringSubmatch(message[2], -1) {
+			wireType, ok := protoWireTypes[field[1]]
+			if !ok {
+				t.Fatalf("tipo %q de %s.%s sin soporte en marshalRecord", field[1], message[1], field[2])
+			}
+			number, _ := strconv.Atoi(field[3])
+			messages[message[1]] = append(messages[message[1]], protoField{name: field[2], number: number, wireType: wireType})
+		}
+	}
+	return messages
+}
+
+// TestMarshalRecord decodifica los mensajes protobuf y compara cada campo con static/records.proto.
+func TestMarshalRecord(t *testing.T) {
+	expiresAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		message string
+		set     recordSet
+		item    renderable
+	}{
+		{"LookupRecord", lookupRecords, lookupItem(sampleLookup("181.0.0.1"), nil)},
+		{"BlockRecord", blockRecords, blockItem(ipinfo.BlockedIP{IP: "1.2.3.4", BlockedAt: expiresAt.Add(-time.Hour), ExpiresAt: &expiresAt})},
+	}
+
+	messages := readProtoMessages(t)
+	for _, tt := range tests {
+		fields := messages[tt.message]
+		if len(fields) != len(tt.set.columns) || len(tt.item.values) != len(tt.set.columns) {
+			t.Fatalf("%s: %d campos en records.proto, %d columnas y %d valores; se esperaba la misma cantidad",
+				tt.message, len(fields), len(tt.set.columns), len(tt.item.values))
+		}
+		byNumber := make(map[protowire.Number]protoField)
+		for i, field := range fields {
+			if field.name != tt.set.columns[i] || field.number != i+1 {
+				t.Errorf("%s: campo %s = %d; se esperaba %s = %d", tt.message, field.name, field.number, tt.set.columns[i], i+1)
+			}
+			byNumber[protowire.Number(field.number)] = field
+		}
+
+		seen := make(map[protowire.Number]bool)
+		b := marshalRecord(tt.item.values)
+		for len(b) > 0 {
+			number, wireType, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatalf("%s: tag invalido: %v", tt.message, protowire.ParseError(n))
+			}
+			b = b[n:]
+			field, ok := byNumber[number]
+			if !ok {
+				t.Fatalf("%s: campo %d no existe en records.proto", tt.message, number)
+			}
+			if wireType != field.wireType {
+				t.Errorf("%s.%s: tipo %d; se esperaba %d", tt.message, field.name, wireType, field.wireType)
+			}
+			if seen[number] {
+				t.Errorf("%s.%s: campo repetido", tt.message, field.name)
+			}
+			seen[number] = true
+
+			var got string
+			switch wireType {
+			case protowire.BytesType:
+				var v []byte
+				v, n = protowire.ConsumeBytes(b)
+				got = string(v)
+			case protowire.VarintType:
+				var v uint64
+				v, n = protowire.ConsumeVarint(b)
+				got = strconv.FormatBool(protowire.DecodeBool(v))
+			case protowire.Fixed64Type:
+				var v uint64
+				v, n = protowire.ConsumeFixed64(b)
+				got = strconv.FormatFloat(math.Float64frombits(v), 'f', -1, 64)
+			default:
+				n = protowire.ConsumeFieldValue(number, wireType, b)
+			}
+			if n < 0 {
+				t.Fatalf("%s.%s: valor invalido: %v", tt.message, field.name, protowire.ParseError(n))
+			}
+			b = b[n:]
+			if want := formatValue(tt.item.values[number-1]); got != want {
+				t.Errorf("%s.%s = %q; se esperaba %q", tt.message, field.name, got, want)
+			}
+		}
+		if len(seen) != len(fields) {
+			t.Errorf("%s: se decodificaron %d campos; se esperaban %d", tt.message, len(seen), len(fields))
+		}
+	}
+}
+
+// TestMarshalRecordZeroValues verifica que los valores cero se omitan salvo los optional no nulos.
+func TestMarshalRecordZeroValues(t *testing.T) {
+	zero := 0.0
+	b := marshalRecord([]interface{}{"", false, 0, 0.0, (*float64)(nil), &zero})
+
+	number, wireType, n := protowire.ConsumeTag(b)
+	if n < 0 || number != 6 || wireType != protowire.Fixed64Type {
+		t.Fatalf("primer campo = %d (tipo %d); se esperaba el campo optional 6 con 0", number, wireType)
+	}
+	if v, m := protowire.ConsumeFixed64(b[n:]); m < 0 || v != 0 || n+m != len(b) {
+		t.Errorf("mensaje = %x; se esperaba solo el campo 6 con 0", b)
+	}
+}
+
+// streamItems escribe items en una lista con newResponseWriter y retorna la respuesta.
+func streamItems(format responseFormat, set recordSet, items []renderable) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	w := newResponseWriter(c, format, set, true)
+	for _, item := range items {
+		w.write(item)
+	}
+	w.close()
+	return recorder
+}
+
+// TestCSVWriter verifica el encabezado y el orden de las columnas de cada fila.
+func TestCSVWriter(t *testing.T) {
+	items := []renderable{lookupItem(sampleLookup("181.0.0.1"), nil), lookupItem(models.LookupResult{IP: "10.0.0.1", Status: models.LookupInvalid}, nil)}
+	recorder := streamItems(formatCSV, lookupRecords, items)
+
+	if got := recorder.Header().Get("Content-Type"); got != contentTypes[formatCSV] {
+		t.Errorf("Content-Type = %q; se esperaba %q", got, contentTypes[formatCSV])
+	}
+	rows, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV invalido: %v", err)
+	}
+	if len(rows) != len(items)+1 {
+		t.Fatalf("se leyeron %d filas; se esperaban %d", len(rows), len(items)+1)
+	}
+	if !reflect.DeepEqual(rows[0], lookupRecords.columns) {
+		t.Errorf("encabezado = %v; se esperaba %v", rows[0], lookupRecords.columns)
+	}
+
+	row := make(map[string]string)
+	for i, column := range rows[0] {
+		row[column] = rows[1][i]
+	}
+	want := map[string]string{
+		"ip": "181.0.0.1", "status": "partial", "country_code": "AR", "meli_country_id": "AR",
+		"currency_id": "ARS", "usd_rate": "0.001", "distance_km": "1234.5", "state_id": "AR-X",
+		"partial": "true", "warnings": "currencies: timeout", "stale": "true", "stale_sources": "country",
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("columna %s = %q; se esperaba %q", column, row[column], value)
+		}
+	}
+	if rows[2][0] != "10.0.0.1" || rows[2][1] != "invalid" || rows[2][15] != "" {
+		t.Errorf("segunda fila = %v; se esperaba la IP invalida sin distancia", rows[2])
+	}
+}
+
+// TestXMLWriter verifica que el XML sea valido y que los valores se escapen.
+func TestXMLWriter(t *testing.T) {
+	result := models.LookupResult{IP: "181.0.0.1", Status: models.LookupUpstreamFailed, Error: `a<b & "c" > 'd'`}
+	item := lookupItem(result, nil)
+	recorder := streamItems(formatXML, lookupRecords, []renderable{item, item})
+
+	body := recorder.Body.String()
+	if strings.Contains(body, "a<b") {
+		t.Errorf("el XML no escapa los valores: %s", body)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	var path []string
+	var elements int
+	values := make(map[string]string)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("XML invalido: %v\n%s", err, body)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			path = append(path, token.Name.Local)
+			if len(path) == 2 {
+				elements++
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		case xml.CharData:
+			if len(path) == 3 {
+				values[path[2]] += string(token)
+			}
+		}
+		if len(path) > 0 && path[0] != lookupRecords.list || len(path) > 1 && path[1] != lookupRecords.element {
+			t.Fatalf("ruta %v; se esperaba %s/%s", path, lookupRecords.list, lookupRecords.element)
+		}
+	}
+	if elements != 2 {
+		t.Errorf("se leyeron %d elementos %s; se esperaban 2", elements, lookupRecords.element)
+	}
+	if values["error"] != result.Error+result.Error {
+		t.Errorf("error = %q; se esperaba %q dos veces", values["error"], result.Error)
+	}
+	if _, ok := values["distance_km"]; ok {
+		t.Errorf("distance_km nulo; se esperaba que se omita")
+	}
+}
+
+// TestStreamedBatch verifica una lista escrita elemento a elemento en NDJSON y en protobuf delimitado.
+func TestStreamedBatch(t *testing.T) {
+	ips := []string{"181.0.0.1", "181.0.0.2", "181.0.0.3"}
+	items := make([]renderable, len(ips))
+	for i, ip := range ips {
+		result := sampleLookup(ip)
+		items[i] = lookupItem(result, gin.H{"ip": ip, "status": result.Status})
+	}
+
+	t.Run("ndjson", func(t *testing.T) {
+		recorder := streamItems(formatNDJSON, lookupRecords, items)
+		if got := recorder.Header().Get("Content-Type"); got != contentTypes[formatNDJSON] {
+			t.Errorf("Content-Type = %q; se esperaba %q", got, contentTypes[formatNDJSON])
+		}
+		lines := strings.Split(strings.TrimSuffix(recorder.Body.String(), "\n"), "\n")
+		if len(lines) != len(ips) {
+			t.Fatalf("se leyeron %d lineas; se esperaban %d", len(lines), len(ips))
+		}
+		for i, line := range lines {
+			var body struct{ IP string }
+			if err := json.Unmarshal([]byte(line), &body); err != nil || body.IP != ips[i] {
+				t.Errorf("linea %d = %s; se esperaba la IP %s", i, line, ips[i])
+			}
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		recorder := streamItems(formatProtobuf, lookupRecords, items)
+		if got := recorder.Header().Get("Content-Type"); got != contentTypes[formatProtobuf] {
+			t.Errorf("Content-Type = %q; se esperaba %q", got, contentTypes[formatProtobuf])
+		}
+		b := recorder.Body.Bytes()
+		for i, item := range items {
+			message, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				t.Fatalf("mensaje %d: longitud invalida: %v", i, protowire.ParseError(n))
+			}
+			b = b[n:]
+			if want := marshalRecord(item.values); !bytes.Equal(message, want) {
+				t.Errorf("mensaje %d = %x; se esperaba %x", i, message, want)
+			}
+		}
+		if len(b) != 0 {
+			t.Errorf("quedaron %d bytes sin leer; se esperaban %d mensajes", len(b), len(items))
+		}
+	})
+}
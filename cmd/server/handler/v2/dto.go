@@ -0,0 +1,245 @@
+// Package v2 define el contrato de respuesta de /api/v2. Los tipos son propios de la API y
+// no dependen del formato de las APIs de MELI ni de IP2Location: todos los campos usan
+// snake_case y los campos opcionales se serializan como null en lugar de omitirse.
+package v2
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/models"
+)
+
+// IPLookup es la respuesta de la consulta de una IP.
+type IPLookup struct {
+	IP           string     `json:"ip"`
+	IPInfo       IPInfo     `json:"ip_info"`       // geolocalizacion segun IP2Location
+	Country      *Country   `json:"country"`       // null si no se pudo obtener el detalle del país
+	Currency     *Currency  `json:"currency"`      // null si no se conoce la moneda del país
+	Region       *Region    `json:"region"`        // null si IP2Location no informa region
+	DistanceKm   *float64   `json:"distance_km"`   // distancia al punto de referencia configurado
+	Localized    *Localized `json:"localized"`     // valores calculados al momento de la consulta
+	Partial      bool       `json:"partial"`       // true si alguna API de MELI fallo
+	Warnings     []Warning  `json:"warnings"`      // fuentes que fallaron
+	Stale        bool       `json:"stale"`         // true si algun dato de MELI se sirvio vencido de la caché
+	StaleSources []string   `json:"stale_sources"` // fuentes servidas vencidas
+}
+
+// IPInfo es la geolocalizacion de la IP.
+type IPInfo struct {
+	CountryCode string `json:"country_code"`
+	CountryName string `json:"country_name"`
+	Region      string `json:"region"`
+	City        string `json:"city"`
+}
+
+// Country es el país de la IP segun MELI.
+type Country struct {
+	ID                 string      `json:"id"`
+	Name               string      `json:"name"`
+	Locale             string      `json:"locale"`
+	CurrencyID         string      `json:"currency_id"`
+	TimeZone           string      `json:"time_zone"`
+	DecimalSeparator   string      `json:"decimal_separator"`
+	ThousandsSeparator string      `json:"thousands_separator"`
+	Location           *Location   `json:"location"`
+	States             []Reference `json:"states"`
+}
+
+// Location es un punto geografico en grados decimales.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Reference es la referencia a una entidad de MELI.
+type Reference struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Currency es la moneda del país y su cotización en USD.
+type Currency struct {
+	ID             string       `json:"id"`
+	USDRate        *float64     `json:"usd_rate"`         // null si no se pudo obtener la cotización
+	USDInverseRate *float64     `json:"usd_inverse_rate"` // null si no se pudo obtener la cotización
+	ValidUntil     *string      `json:"valid_until"`      // null si no se pudo obtener la cotización
+	Conversions    []Conversion `json:"conversions"`      // solo con ?to=
+}
+
+// Conversion es un monto convertido a otra moneda.
+type Conversion struct {
+	To              string  `json:"to"`
+	Rate            float64 `json:"rate"`
+	Amount          float64 `json:"amount"`
+	ConvertedAmount float64 `json:"converted_amount"`
+	Symbol          string  `json:"symbol"`
+	DecimalPlaces   int     `json:"decimal_places"`
+}
+
+// Region asocia la region de la IP con un estado de MELI.
+type Region struct {
+	Name      string  `json:"name"`
+	City      string  `json:"city"`
+	StateID   *string `json:"state_id"`
+	StateName *string `json:"state_name"`
+}
+
+// Localized son valores formateados para el país.
+type Localized struct {
+	LocalTime          string `json:"local_time"`
+	LocalTimeFormatted string `json:"local_time_formatted"`
+	SampleAmount       string `json:"sample_amount"`
+	SampleAmountUSD    string `json:"sample_amount_usd"`
+}
+
+// Warning describe una fuente que no pudo consultarse. Message esta en el idioma de la respuesta;
+// Debug es el texto original del error, sin traducir.
+type Warning struct {
+	Source  string `json:"source"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	Debug   string `json:"debug,omitempty"`
+}
+
+// LookupResult es el resultado de una IP dentro de un lote.
+type LookupResult struct {
+	IP             string               `json:"ip"`
+	Status         string               `json:"status"`
+	Data           *IPLookup            `json:"data"`
+	Error          *Error               `json:"error"`
+	SpecialPurpose *SpecialPurposeRange `json:"special_purpose"`
+}
+
+// Error es el detalle de un error dentro de un lote.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// SpecialPurposeRange es el rango de IANA de una IP de proposito especial.
+type SpecialPurposeRange struct {
+	Network  string `json:"network"`
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	RFC      string `json:"rfc"`
+}
+
+// BatchLookupResponse es la respuesta de una consulta en lote.
+type BatchLookupResponse struct {
+	Count   int            `json:"count"`
+	Summary map[string]int `json:"summary"` // cantidad de resultados por estado
+	Results []LookupResult `json:"results"`
+}
+
+// NewIPLookup convierte la información del servicio al contrato de v2.
+func NewIPLookup(ip string, info *models.CountryInfo) IPLookup {
+	lookup := IPLookup{
+		IP:           ip,
+		DistanceKm:   info.DistanceKm,
+		Partial:      info.Partial,
+		Warnings:     make([]Warning, 0, len(info.Warnings)),
+		Stale:        info.Stale,
+		StaleSources: append(make([]string, 0, len(info.StaleSources)), info.StaleSources...),
+	}
+
+	if info.IPInfo != nil {
+		lookup.IPInfo = IPInfo{
+			CountryCode: info.IPInfo.CountryCode,
+			CountryName: info.IPInfo.CountryName,
+			Region:      info.IPInfo.Region,
+			City:        info.IPInfo.City,
+		}
+	} else {
+		lookup.IPInfo = IPInfo{CountryCode: info.ID, CountryName: info.Name}
+	}
+
+	// Sin moneda el detalle del país no se obtuvo (respuesta parcial)
+	if info.CurrencyId != "" {
+		country := &Country{
+			ID:                 info.ID,
+			Name:               info.Name,
+			Locale:             info.Locale,
+			CurrencyID:         info.CurrencyId,
+			TimeZone:           info.TimeZone,
+			DecimalSeparator:   info.DecimalSeparator,
+			ThousandsSeparator: info.ThousandsSeparator,
+			States:             make([]Reference, 0, len(info.States)),
+		}
+		if info.GeoInformation != nil {
+			country.Location = &Location{Latitude: info.GeoInformation.Location.Latitude, Longitude: info.GeoInformation.Location.Longitude}
+		}
+		for _, state := range info.States {
+			country.States = append(country.States, Reference{ID: state.ID, Name: state.Name})
+		}
+		lookup.Country = country
+	}
+
+	// La moneda se informa aunque falle su cotización, con las conversiones que se hayan obtenido
+	if info.CurrencyId != "" || len(info.Conversions) > 0 {
+		currency := &Currency{
+			ID:          info.CurrencyId,
+			Conversions: make([]Conversion, 0, len(info.Conversions)),
+		}
+		if rate := info.CurrencyConversionToUSD; rate != nil {
+			currency.USDRate, currency.USDInverseRate, currency.ValidUntil = &rate.Rate, &rate.InverseRate, &rate.ValidUntil
+		}
+		for _, conversion := range info.Conversions {
+			currency.Conversions = append(currency.Conversions, Conversion{
+				To:              conversion.To,
+				Rate:            conversion.Rate,
+				Amount:          conversion.Amount,
+				ConvertedAmount: conversion.ConvertedAmount,
+				Symbol:          conversion.Symbol,
+				DecimalPlaces:   conversion.DecimalPlaces,
+			})
+		}
+		lookup.Currency = currency
+	}
+
+	if region := info.Region; region != nil {
+		lookup.Region = &Region{Name: region.Region, City: region.City}
+		if region.StateID != "" {
+			lookup.Region.StateID, lookup.Region.StateName = &region.StateID, &region.StateName
+		}
+	}
+
+	if localized := info.Localized; localized != nil {
+		lookup.Localized = &Localized{
+			LocalTime:          localized.LocalTime,
+			LocalTimeFormatted: localized.LocalTimeFormatted,
+			SampleAmount:       localized.SampleAmount,
+			SampleAmountUSD:    localized.SampleAmountUSD,
+		}
+	}
+
+	for _, warning := range info.Warnings {
+		lookup.Warnings = append(lookup.Warnings, Warning{Source: warning.Source, Code: warning.Code, Message: warning.Error, Debug: warning.Debug})
+	}
+	return lookup
+}
+
+// NewLookupResult convierte el resultado de una IP de un lote al contrato de v2.
+func NewLookupResult(result models.LookupResult) LookupResult {
+	converted := LookupResult{IP: result.IP, Status: string(result.Status)}
+	if result.Data != nil {
+		lookup := NewIPLookup(result.IP, result.Data)
+		converted.Data = &lookup
+	}
+	if result.Error != "" {
+		converted.Error = &Error{Code: result.Code, Message: result.Error}
+	}
+	if sp := result.SpecialPurpose; sp != nil {
+		converted.SpecialPurpose = &SpecialPurposeRange{Network: sp.Network, Category: sp.Category, Name: sp.Name, RFC: sp.RFC}
+	}
+	return converted
+}
+
+// NewBatchLookupResponse crea una respuesta de lote vacia.
+func NewBatchLookupResponse() *BatchLookupResponse {
+	return &BatchLookupResponse{Summary: map[string]int{}, Results: []LookupResult{}}
+}
+
+// Add agrega un resultado a la respuesta.
+func (b *BatchLookupResponse) Add(result models.LookupResult) {
+	b.Count++
+	b.Summary[string(result.Status)]++
+	b.Results = append(b.Results, NewLookupResult(result))
+}
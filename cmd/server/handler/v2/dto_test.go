@@ -0,0 +1,34 @@
+package v2
+
+import (
+	"encoding/json"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"testing"
+)
+
+// TestNewIPLookupCurrency verifica que la moneda se informa si se conoce el país aunque falle su
+// cotización, con la cotización en null y las conversiones obtenidas.
+func TestNewIPLookupCurrency(t *testing.T) {
+	country := models.Country{ID: "AR", Name: "Argentina", CurrencyId: "ARS"}
+	conversions := []models.CurrencyConversion{{To: "BRL", Rate: 0.006, Amount: 100, ConvertedAmount: 0.6}}
+
+	tests := []struct {
+		name string
+		info *models.CountryInfo
+		want string
+	}{
+		{"con cotización", &models.CountryInfo{Country: country, CurrencyConversionToUSD: &models.CurrencyExchange{Rate: 0.001, InverseRate: 1000, ValidUntil: "2024-12-20T13:10:00Z"}},
+			`{"id":"ARS","usd_rate":0.001,"usd_inverse_rate":1000,"valid_until":"2024-12-20T13:10:00Z","conversions":[]}`},
+		{"sin cotización", &models.CountryInfo{Country: country},
+			`{"id":"ARS","usd_rate":null,"usd_inverse_rate":null,"valid_until":null,"conversions":[]}`},
+		{"sin cotización con conversiones", &models.CountryInfo{Country: country, Conversions: conversions},
+			`{"id":"ARS","usd_rate":null,"usd_inverse_rate":null,"valid_until":null,"conversions":[{"to":"BRL","rate":0.006,"amount":100,"converted_amount":0.6,"symbol":"","decimal_places":0}]}`},
+		{"sin detalle del país", &models.CountryInfo{Country: models.Country{ID: "AR", Name: "Argentina"}}, `null`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(NewIPLookup("1.2.3.4", tt.info).Currency)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: currency = %s, se esperaba %s", tt.name, got, tt.want)
+		}
+	}
+}
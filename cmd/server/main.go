@@ -7,9 +7,9 @@ import (
 	"github.com/AleHts29/meli-challenge/internal/ipinfo"
 	"github.com/AleHts29/meli-challenge/pkg/api"
 	"github.com/AleHts29/meli-challenge/pkg/store"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"log"
+	_ "time/tzdata" // base de zonas horarias embebida en el binario
 )
 
 func main() {
@@ -24,32 +24,30 @@ func main() {
 	ipStore, err := store.NewIpStore(cfg.IPStorePath)
 	if err != nil {
 		panic(err)
-		return
 	}
 
 	// creacion de instancias
-	apiCountries := api.NewCountries(cfg.APIKey, cfg.APIUrl)
-	apiCurrencies := api.NewCurrencies(cfg.APIKey, cfg.APIUrl)
-	repository := ipinfo.NewRepository(apiCountries, apiCurrencies, ipStore)
-	service := ipinfo.NewService(repository, cfg.BlockedIPsFilePath)
-	newHandler := handler.NewHandler(service)
-
-	router := gin.Default()
-
-	// Habilitar CORS
-	router.Use(cors.Default())
-
-	// Configurar rutas y servidor
-	router.Static("/static", "./static")
-	router.GET("/", func(c *gin.Context) {
-		c.File("./static/index.html")
+	// Cliente HTTP compartido por todas las consultas a la API de MELI
+	apiClient := api.NewClient(api.ClientConfig{
+		TokenSource:  newTokenSource(cfg),
+		Timeout:      cfg.UpstreamTimeout,
+		Timeouts:     cfg.UpstreamTimeouts,
+		MaxRetries:   cfg.UpstreamMaxRetries,
+		BackoffBase:  cfg.UpstreamBackoffBase,
+		BackoffMax:   cfg.UpstreamBackoffMax,
+		MaxIdleConns: cfg.UpstreamMaxIdleConns,
+		Mode:         cfg.UpstreamMode,
+		FixturesDir:  cfg.FixturesDir,
 	})
+	apiCountries := api.NewCountries(cfg.APIUrl, apiClient)
+	apiCurrencies := api.NewCurrencies(cfg.APIUrl, apiClient)
+	repository := ipinfo.NewRepository(apiCountries, apiCurrencies, ipStore)
+	service := ipinfo.NewService(repository, cfg)
+	newHandler := handler.NewHandler(service, cfg)
 
-	ip := router.Group("/api/ip")
-	{
-		ip.GET("/:ip", newHandler.GetCountryByIP())      // Obtener informacion de paises mediante una IP
-		ip.POST("/block", newHandler.BlockIPs())         // Bloquear una o varias IPs
-		ip.GET("/events", newHandler.NotifyBlockedIPs()) // Emitir eventos de bloqueo
+	router, err := newRouter(newHandler, cfg)
+	if err != nil {
+		panic(err)
 	}
 
 	// Iniciar el servidor
@@ -58,3 +56,27 @@ func main() {
 		log.Fatalf("Error al iniciar el servidor: %v", err)
 	}
 }
+
+// newTokenSource elige la autenticacion de las consultas a MELI: OAuth si se configuro
+// MELI_CLIENT_ID, el token fijo de API_KEY si se habilito con API_KEY_AUTH, o ninguna. Las APIs
+// publicas de MELI rechazan un token invalido con 401, por lo que sin configurar nada no se envia
+// token. Con fixtures (replay) no se autentica.
+func newTokenSource(cfg *config.Config) api.TokenSource {
+	switch {
+	case cfg.UpstreamMode == api.ModeReplay:
+		return nil
+	case cfg.MeliClientID != "":
+		return api.NewOAuthTokenSource(api.OAuthConfig{
+			TokenURL:         cfg.MeliTokenURL,
+			ClientID:         cfg.MeliClientID,
+			ClientSecret:     cfg.MeliClientSecret,
+			RefreshToken:     cfg.MeliRefreshToken,
+			RefreshBefore:    cfg.MeliRefreshBefore,
+			RefreshTokenFile: cfg.MeliRefreshFile,
+		})
+	case cfg.APIKeyAuth && cfg.APIKey != "":
+		return api.StaticToken(cfg.APIKey)
+	default:
+		return nil
+	}
+}
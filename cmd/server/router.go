@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/AleHts29/meli-challenge/cmd/server/handler"
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter registra todas las rutas del servidor. Cada ruta debe estar documentada en
+// handler.OpenAPISpec; router_test.go falla si las rutas y la especificacion difieren.
+func newRouter(newHandler *handler.Handler, cfg *config.Config) (*gin.Engine, error) {
+	router := gin.Default()
+
+	// Solo se confia en los headers de proxies configurados en TRUSTED_PROXIES
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	// y solo del header configurado en TRUSTED_PROXY_HEADER; gin no interpreta Forwarded
+	router.RemoteIPHeaders = nil
+	if cfg.TrustedProxyHeader == config.HeaderXForwardedFor {
+		router.RemoteIPHeaders = []string{config.HeaderXForwardedFor}
+	}
+
+	// Habilitar CORS
+	router.Use(cors.Default())
+
+	// Configurar rutas y servidor
+	router.Static("/static", "./static")
+	router.GET("/", func(c *gin.Context) {
+		c.File("./static/index.html")
+	})
+
+	// Documentacion de la API
+	router.GET("/openapi.json", newHandler.GetOpenAPISpec())    // Especificacion OpenAPI 3
+	router.GET("/docs", newHandler.GetDocs())                   // Pagina de documentacion (Swagger UI)
+	router.GET("/docs/assets/:file", newHandler.GetDocsAsset()) // Archivos de Swagger UI embebidos
+
+	// Estado del servicio y de las APIs externas
+	router.GET("/health", newHandler.GetHealth())   // Estado de los circuit breakers y del refresco de datos de MELI
+	router.GET("/metrics", newHandler.GetMetrics()) // Metricas en formato Prometheus
+
+	// Los eventos son una conexion de larga duracion, por lo que no tienen plazo
+	router.GET("/api/ip/events", newHandler.NotifyBlockedIPs()) // Emitir eventos de bloqueo y consultas denegadas
+
+	// El resto de la API tiene un plazo por solicitud (REQUEST_TIMEOUT)
+	api := router.Group("/api", newHandler.RequestDeadline())
+
+	ip := api.Group("/ip")
+	{
+		ip.GET("/me", newHandler.GetCallerCountry())    // Obtener informacion del pais desde el que se origina la solicitud
+		ip.GET("/:ip", newHandler.GetCountryByIP())     // Obtener informacion de paises mediante una IP
+		ip.POST("/lookup", newHandler.LookupIPs())      // Obtener informacion de paises para un lote de IPs
+		ip.GET("/block", newHandler.ListBlockedIPs())   // Listar los bloqueos vigentes
+		ip.POST("/block", newHandler.BlockIPs())        // Bloquear una o varias IPs
+		ip.DELETE("/block/:ip", newHandler.UnblockIP()) // Desbloquear una IP
+	}
+
+	// v2: contrato de respuesta propio, independiente de las APIs de MELI
+	ipV2 := api.Group("/v2/ip")
+	{
+		ipV2.GET("/me", newHandler.GetCallerCountryV2()) // Informacion del pais de la IP que origina la solicitud
+		ipV2.GET("/:ip", newHandler.GetCountryByIPV2())  // Informacion del pais de una IP
+		ipV2.POST("/lookup", newHandler.LookupIPsV2())   // Informacion de paises para un lote de IPs
+	}
+
+	countries := api.Group("/countries")
+	{
+		countries.GET("", newHandler.GetCountries())   // Paises en los que opera MELI
+		countries.GET("/:id", newHandler.GetCountry()) // Detalle de un pais con sus estados
+	}
+
+	api.GET("/states/:id", newHandler.GetState()) // Detalle de un estado con sus ciudades
+	api.GET("/cities/:id", newHandler.GetCity())  // Detalle de una ciudad con sus barrios
+
+	currencies := api.Group("/currencies")
+	{
+		currencies.GET("", newHandler.GetCurrencies())            // Monedas de MELI
+		currencies.GET("/:id/rate", newHandler.GetCurrencyRate()) // Cotizacion de una moneda (?to=, por defecto USD)
+	}
+
+	api.GET("/stats", newHandler.GetStats()) // Estadisticas de consultas y distancias
+
+	return router, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/AleHts29/meli-challenge/cmd/server/handler"
+	"github.com/AleHts29/meli-challenge/internal/config"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPISpecMatchesRoutes falla si se registra una ruta sin documentarla en
+// handler.OpenAPISpec, o si la especificacion documenta una ruta que no existe.
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	router, err := newRouter(handler.NewHandler(nil, cfg), cfg)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		// Los archivos estaticos y los HEAD que agrega router.Static no forman parte de la API
+		if route.Method == http.MethodHead || strings.HasPrefix(route.Path, "/static/") {
+			continue
+		}
+		registered[route.Method+" "+handler.OpenAPIPath(route.Path)] = true
+	}
+
+	documented := map[string]bool{}
+	for path, operations := range handler.OpenAPISpec().Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for route := range registered {
+		if !documented[route] {
+			t.Errorf("la ruta %s no está documentada en la especificación OpenAPI", route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			t.Errorf("la especificación OpenAPI documenta %s, que no está registrada en el router", route)
+		}
+	}
+}
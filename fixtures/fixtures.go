@@ -0,0 +1,10 @@
+// Package fixtures embebe los fixtures de la API de MELI versionados en el repositorio, para que
+// puedan usarse sin depender del directorio de trabajo (por ejemplo, como datos de cmd/mockmeli).
+package fixtures
+
+import "embed"
+
+// Meli contiene las respuestas de la API de MELI de meli/, con el formato de api.NewReplayTransport.
+//
+//go:embed meli/*.json
+var Meli embed.FS
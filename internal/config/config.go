@@ -1,16 +1,71 @@
 package config
 
 import (
+	"fmt"
 	"github.com/joho/godotenv"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers admitidos en TRUSTED_PROXY_HEADER.
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded" // RFC 7239
 )
 
 type Config struct {
-	APIKey             string
+	APIKey             string // access token fijo de MELI; solo se envia con APIKeyAuth y se ignora si se configura OAuth
+	APIKeyAuth         bool   // habilita el envio de APIKey a MELI
 	ServerPort         string
 	APIUrl             string
 	IPStorePath        string
 	BlockedIPsFilePath string
+	BatchMaxIPs        int      // cantidad maxima de IPs por consulta en lote
+	BatchConcurrency   int      // consultas en paralelo al resolver un lote
+	TrustedProxies     []string // CIDRs de proxies de confianza para X-Forwarded-For / Forwarded
+	TrustedProxyHeader string   // header con la IP del cliente que escriben los proxies de confianza
+	ReferenceLatitude  float64  // punto de referencia para calcular distancias (por defecto Buenos Aires)
+	ReferenceLongitude float64
+	RequestTimeout     time.Duration // plazo de cada solicitud, incluidas las consultas a las APIs externas (0 = sin plazo)
+
+	// Cliente HTTP de la API de MELI
+	UpstreamTimeout      time.Duration            // plazo de cada intento
+	UpstreamTimeouts     map[string]time.Duration // plazo de cada intento por operacion ("countries=2s,currency_conversion=1s")
+	UpstreamMaxRetries   int                      // reintentos ante errores de red, 5xx y 429 (0 = sin reintentos)
+	UpstreamBackoffBase  time.Duration            // espera maxima antes del primer reintento, se duplica en cada uno
+	UpstreamBackoffMax   time.Duration            // tope de la espera entre reintentos
+	UpstreamMaxIdleConns int                      // conexiones inactivas que se mantienen abiertas
+	UpstreamMode         string                   // live, record (graba las respuestas en FixturesDir) o replay (responde desde FixturesDir)
+	FixturesDir          string                   // directorio de las respuestas grabadas de MELI
+
+	// OAuth de MELI: con MeliClientID se obtienen y renuevan access tokens automaticamente
+	MeliTokenURL      string
+	MeliClientID      string
+	MeliClientSecret  string
+	MeliRefreshToken  string        // refresh token inicial; vacio = client_credentials
+	MeliRefreshBefore time.Duration // margen antes del vencimiento en el que se renueva el token
+	MeliRefreshFile   string        // archivo donde se guarda el refresh token vigente (MELI lo rota en cada renovacion)
+
+	// Tiempos de vida de la caché por tipo de dato
+	CacheTTL           time.Duration // resultado completo de una IP
+	CacheTTLCountries  time.Duration // lista de países de MELI
+	CacheTTLCountry    time.Duration // detalle de un país
+	CacheTTLLocation   time.Duration // detalle de estados y ciudades
+	CacheTTLCurrencies time.Duration // lista de monedas
+	CacheTTLRate       time.Duration // cotizaciones sin valid_until (las demas vencen en su valid_until)
+	CacheStaleTTL      time.Duration // margen en el que un dato de referencia vencido se sirve mientras se refresca
+
+	// Precarga y refresco en segundo plano de los datos de referencia de MELI
+	RefreshInterval time.Duration // cada cuanto se revisan los vencimientos (0 = sin precarga ni refresco)
+	RefreshAhead    time.Duration // margen antes del vencimiento en el que se refresca un dato
+
+	// Circuit breakers de las operaciones de MELI
+	BreakerFailureThreshold int           // fallas consecutivas que abren el breaker
+	BreakerOpenTimeout      time.Duration // tiempo abierto antes de permitir una consulta de prueba
 }
 
 func LoadConfig() (*Config, error) {
@@ -21,17 +76,165 @@ func LoadConfig() (*Config, error) {
 	// Obtener variables de entorno o carga de parametros por default
 	config := &Config{
 		APIKey:             getEnvironment("API_KEY", ""),
+		APIKeyAuth:         getEnvironmentBool("API_KEY_AUTH", false),
 		ServerPort:         getEnvironment("SERVER_PORT", "9090"),
 		APIUrl:             getEnvironment("API_URL", ""),
 		IPStorePath:        getEnvironment("IP_STORE_PATH", "./-LITE-DB1.BIN"),
 		BlockedIPsFilePath: getEnvironment("BLOCKED_IPS_FILE_PATH", "./.json"),
+		BatchMaxIPs:        getEnvironmentInt("BATCH_MAX_IPS", 1000),
+		BatchConcurrency:   getEnvironmentInt("BATCH_CONCURRENCY", 8),
+		TrustedProxies:     getEnvironmentList("TRUSTED_PROXIES"),
+		TrustedProxyHeader: http.CanonicalHeaderKey(getEnvironment("TRUSTED_PROXY_HEADER", HeaderXForwardedFor)),
+		ReferenceLatitude:  getEnvironmentFloat("REFERENCE_LATITUDE", -34.6037),
+		ReferenceLongitude: getEnvironmentFloat("REFERENCE_LONGITUDE", -58.3816),
+		RequestTimeout:     getEnvironmentDuration("REQUEST_TIMEOUT", 10*time.Second),
+
+		UpstreamTimeout:      getEnvironmentDuration("UPSTREAM_TIMEOUT", 3*time.Second),
+		UpstreamMaxRetries:   getEnvironmentInt("UPSTREAM_MAX_RETRIES", 2),
+		UpstreamBackoffBase:  getEnvironmentDuration("UPSTREAM_BACKOFF_BASE", 100*time.Millisecond),
+		UpstreamBackoffMax:   getEnvironmentDuration("UPSTREAM_BACKOFF_MAX", 2*time.Second),
+		UpstreamMaxIdleConns: getEnvironmentInt("UPSTREAM_MAX_IDLE_CONNS", 100),
+		UpstreamMode:         strings.ToLower(getEnvironment("UPSTREAM_MODE", "live")),
+		FixturesDir:          getEnvironment("FIXTURES_DIR", "./fixtures/meli"),
+
+		MeliTokenURL:      getEnvironment("MELI_TOKEN_URL", "https://api.mercadolibre.com/oauth/token"),
+		MeliClientID:      getEnvironment("MELI_CLIENT_ID", ""),
+		MeliClientSecret:  getEnvironment("MELI_CLIENT_SECRET", ""),
+		MeliRefreshToken:  getEnvironment("MELI_REFRESH_TOKEN", ""),
+		MeliRefreshBefore: getEnvironmentDuration("MELI_TOKEN_REFRESH_BEFORE", time.Minute),
+		MeliRefreshFile:   getEnvironment("MELI_REFRESH_TOKEN_FILE", ""),
+
+		CacheTTL:           getEnvironmentDuration("CACHE_TTL", 5*time.Minute),
+		CacheTTLCountries:  getEnvironmentDuration("CACHE_TTL_COUNTRIES", 24*time.Hour),
+		CacheTTLCountry:    getEnvironmentDuration("CACHE_TTL_COUNTRY", 24*time.Hour),
+		CacheTTLLocation:   getEnvironmentDuration("CACHE_TTL_LOCATION", 24*time.Hour),
+		CacheTTLCurrencies: getEnvironmentDuration("CACHE_TTL_CURRENCIES", 24*time.Hour),
+		CacheTTLRate:       getEnvironmentDuration("CACHE_TTL_RATE", 5*time.Minute),
+		CacheStaleTTL:      getEnvironmentDuration("CACHE_STALE_TTL", 24*time.Hour),
+
+		RefreshInterval: getEnvironmentDuration("REFRESH_INTERVAL", 30*time.Second),
+		RefreshAhead:    getEnvironmentDuration("REFRESH_AHEAD", 2*time.Minute),
+
+		BreakerFailureThreshold: getEnvironmentInt("BREAKER_FAILURE_THRESHOLD", 5),
+		BreakerOpenTimeout:      getEnvironmentDuration("BREAKER_OPEN_TIMEOUT", 30*time.Second),
+	}
+
+	timeouts, err := parseDurations(getEnvironmentList("UPSTREAM_TIMEOUTS"))
+	if err != nil {
+		return nil, fmt.Errorf("UPSTREAM_TIMEOUTS: %w", err)
+	}
+	config.UpstreamTimeouts = timeouts
+
+	// Con un maximo o una concurrencia menor a 1 todas las consultas en lote fallarian
+	if config.BatchMaxIPs < 1 {
+		return nil, fmt.Errorf("BATCH_MAX_IPS: debe ser mayor a 0, se configuro %d", config.BatchMaxIPs)
+	}
+	if config.BatchConcurrency < 1 {
+		return nil, fmt.Errorf("BATCH_CONCURRENCY: debe ser mayor a 0, se configuro %d", config.BatchConcurrency)
+	}
+
+	switch config.UpstreamMode {
+	case "live", "record", "replay":
+	default:
+		return nil, fmt.Errorf("UPSTREAM_MODE: '%s' no es live, record ni replay", config.UpstreamMode)
+	}
+
+	// Se lee un unico header: si se leyera otro que el proxy no escribe, el cliente podria falsificarlo
+	switch config.TrustedProxyHeader {
+	case HeaderXForwardedFor, HeaderForwarded:
+	default:
+		return nil, fmt.Errorf("TRUSTED_PROXY_HEADER: '%s' no es %s ni %s", config.TrustedProxyHeader, HeaderXForwardedFor, HeaderForwarded)
+	}
+
+	for _, cidr := range config.TrustedProxies {
+		if _, err := ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
 	}
 	return config, nil
 }
 
+// ParseCIDR interpreta un CIDR o una IP suelta (equivalente a /32 o /128).
+func ParseCIDR(value string) (*net.IPNet, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipNet, err := net.ParseCIDR(value)
+	return ipNet, err
+}
+
 func getEnvironment(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value // Si la variable existe, devuelve su valor
 	}
 	return defaultValue // Si no existe, devuelve el valor por defecto
 }
+
+// getEnvironmentInt obtiene una variable de entorno numerica, o el valor por defecto si no existe o no es valida.
+func getEnvironmentInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnvironment(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvironmentBool obtiene una variable de entorno booleana ("true", "1", "false"...), o el valor por defecto si no existe o no es valida.
+func getEnvironmentBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getEnvironment(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvironmentFloat obtiene una variable de entorno decimal, o el valor por defecto si no existe o no es valida.
+func getEnvironmentFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnvironment(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvironmentDuration obtiene una variable de entorno con una duracion ("500ms", "10s"),
+// o el valor por defecto si no existe o no es valida.
+func getEnvironmentDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnvironment(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// parseDurations interpreta una lista de pares "clave=duracion".
+func parseDurations(values []string) (map[string]time.Duration, error) {
+	durations := make(map[string]time.Duration, len(values))
+	for _, value := range values {
+		key, duration, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("'%s' no tiene el formato clave=duracion", value)
+		}
+		parsed, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %w", value, err)
+		}
+		durations[strings.TrimSpace(key)] = parsed
+	}
+	return durations, nil
+}
+
+// getEnvironmentList obtiene una variable de entorno con valores separados por coma.
+func getEnvironmentList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnvironment(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
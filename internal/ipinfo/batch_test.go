@@ -0,0 +1,136 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// batchRepository cuenta las consultas de cada dato de MELI y la cantidad maxima de
+// geolocalizaciones en curso a la vez.
+type batchRepository struct {
+	*countingRepository
+	inFlight, maxInFlight    atomic.Int64
+	countries, country, rate atomic.Int64
+}
+
+func (r *batchRepository) GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error) {
+	n := r.inFlight.Add(1)
+	defer r.inFlight.Add(-1)
+	for max := r.maxInFlight.Load(); n > max && !r.maxInFlight.CompareAndSwap(max, n); max = r.maxInFlight.Load() {
+	}
+	return r.countingRepository.GetCountryByIP(ctx, ip)
+}
+
+func (r *batchRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	r.countries.Add(1)
+	return r.countingRepository.FetchCountries(ctx)
+}
+
+func (r *batchRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	r.country.Add(1)
+	return r.countingRepository.FetchCountryById(ctx, countryID)
+}
+
+func (r *batchRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	r.rate.Add(1)
+	return r.countingRepository.FetchCurrencyConversion(ctx, from, to)
+}
+
+// batchIPs retorna n IPs publicas distintas.
+func batchIPs(n int) []string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("200.1.%d.%d", i>>8&0xff, i&0xff)
+	}
+	return ips
+}
+
+// collect lee todos los resultados de un lote, indexados por IP.
+func collect(t *testing.T, results <-chan models.LookupResult) map[string]models.LookupResult {
+	t.Helper()
+	byIP := make(map[string]models.LookupResult)
+	for result := range results {
+		if _, ok := byIP[result.IP]; ok {
+			t.Errorf("%s se informo mas de una vez", result.IP)
+		}
+		byIP[result.IP] = result
+	}
+	return byIP
+}
+
+// TestLookupBatchConcurrency verifica que un lote no resuelve mas IPs en paralelo que las
+// indicadas, que una concurrencia menor a 1 resuelve de a una y que se informan todas las IPs.
+func TestLookupBatchConcurrency(t *testing.T) {
+	for _, tt := range []struct{ concurrency, want int64 }{{4, 4}, {0, 1}, {-3, 1}} {
+		repo := &batchRepository{countingRepository: &countingRepository{latency: 5 * time.Millisecond}}
+		s := newBenchmarkService(repo, false)
+		ips := batchIPs(16)
+
+		results := collect(t, s.LookupBatch(context.Background(), ips, int(tt.concurrency), LookupOptions{}))
+		if len(results) != len(ips) {
+			t.Fatalf("concurrencia %d: se esperaban %d resultados, se obtuvieron %d", tt.concurrency, len(ips), len(results))
+		}
+		for _, ip := range ips {
+			if results[ip].Status != models.LookupOK {
+				t.Errorf("concurrencia %d: %s = %+v", tt.concurrency, ip, results[ip])
+			}
+		}
+		// Con latencia, 16 IPs alcanzan a ocupar mas de un lugar si la concurrencia lo permite
+		if got := repo.maxInFlight.Load(); got > tt.want || (tt.want > 1 && got < 2) {
+			t.Errorf("concurrencia %d: se resolvieron %d IPs en paralelo, se esperaban hasta %d", tt.concurrency, got, tt.want)
+		}
+	}
+}
+
+// TestLookupBatchSharesReferenceData verifica que las IPs de un lote comparten las consultas de
+// los datos de MELI: una por dato aunque las IPs se resuelvan en paralelo.
+func TestLookupBatchSharesReferenceData(t *testing.T) {
+	repo := &batchRepository{countingRepository: &countingRepository{latency: 5 * time.Millisecond}}
+	s := newBenchmarkService(repo, false)
+	ips := batchIPs(32)
+
+	results := collect(t, s.LookupBatch(context.Background(), ips, 8, LookupOptions{}))
+	if len(results) != len(ips) {
+		t.Fatalf("se esperaban %d resultados, se obtuvieron %d", len(ips), len(results))
+	}
+	if countries, country, rate := repo.countries.Load(), repo.country.Load(), repo.rate.Load(); countries != 1 || country != 1 || rate != 1 {
+		t.Fatalf("se esperaba una consulta por dato de MELI, se hicieron countries=%d country=%d rate=%d", countries, country, rate)
+	}
+
+	// Cada lote comparte sus propias consultas: uno nuevo vuelve a consultar los datos
+	collect(t, s.LookupBatch(context.Background(), batchIPs(64)[32:], 8, LookupOptions{}))
+	if countries := repo.countries.Load(); countries != 2 {
+		t.Fatalf("se esperaba una consulta de países por lote, se hicieron %d", countries)
+	}
+}
+
+// TestLookupBatchCanceled verifica que al cancelarse la solicitud las IPs que no se llegaron a
+// resolver se informan como falla de MELI.
+func TestLookupBatchCanceled(t *testing.T) {
+	repo := &batchRepository{countingRepository: &countingRepository{latency: time.Second}}
+	s := newBenchmarkService(repo, false)
+	ips := batchIPs(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := collect(t, s.LookupBatch(ctx, ips, 2, LookupOptions{}))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("el lote no termino al cancelarse la solicitud (%s)", elapsed)
+	}
+	if len(results) != len(ips) {
+		t.Fatalf("se esperaban %d resultados, se obtuvieron %d", len(ips), len(results))
+	}
+	for _, ip := range ips {
+		if results[ip].Status != models.LookupUpstreamFailed {
+			t.Errorf("%s = %s, se esperaba %s", ip, results[ip].Status, models.LookupUpstreamFailed)
+		}
+	}
+	if got := repo.maxInFlight.Load(); got > 2 {
+		t.Errorf("se resolvieron %d IPs en paralelo con concurrencia 2", got)
+	}
+}
@@ -1,38 +1,65 @@
 package ipinfo
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
+
+// BlockedIP representa una IP bloqueada. ExpiresAt es nil si el bloqueo es permanente.
+type BlockedIP struct {
+	IP        string     `json:"ip"`
+	BlockedAt time.Time  `json:"blocked_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// expired indica si el bloqueo vencio en el instante now.
+func (b BlockedIP) expired(now time.Time) bool {
+	return b.ExpiresAt != nil && !now.Before(*b.ExpiresAt)
+}
 
 // BlockList maneja la lista de IPs bloqueadas.
 type BlockList struct {
-	blockedIPs map[string]bool
+	blockedIPs map[string]BlockedIP
 	mu         sync.Mutex
 }
 
 // NewBlockList crea una nueva instancia de la lista de IPs bloqueadas.
 func NewBlockList() *BlockList {
 	return &BlockList{
-		blockedIPs: make(map[string]bool),
+		blockedIPs: make(map[string]BlockedIP),
 	}
 }
 
 // GetAll retorna la lista de IPs bloqueadas
-func (bl *BlockList) GetAll() []string {
+func (bl *BlockList) GetAll() []BlockedIP {
 	bl.mu.Lock()
 	defer bl.mu.Unlock()
 
-	blockedIPs := make([]string, 0, len(bl.blockedIPs))
-	for ip := range bl.blockedIPs {
-		blockedIPs = append(blockedIPs, ip)
+	blockedIPs := make([]BlockedIP, 0, len(bl.blockedIPs))
+	for _, entry := range bl.blockedIPs {
+		blockedIPs = append(blockedIPs, entry)
 	}
 	return blockedIPs
 }
 
-// AddIP bloquea una IP
-func (bl *BlockList) AddIP(ip string) {
+// AddIP bloquea una IP. Si ttl es cero el bloqueo es permanente.
+func (bl *BlockList) AddIP(ip string, ttl time.Duration) BlockedIP {
+	now := time.Now()
+	entry := BlockedIP{IP: ip, BlockedAt: now}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+	bl.Restore(entry)
+	return entry
+}
+
+// Restore agrega una entrada tal cual fue persistida.
+func (bl *BlockList) Restore(entry BlockedIP) {
 	bl.mu.Lock()
 	defer bl.mu.Unlock()
 
-	bl.blockedIPs[ip] = true
+	bl.blockedIPs[entry.IP] = entry
 }
 
 // IsBlocked verifica si una IP está bloqueada.
@@ -40,13 +67,31 @@ func (bl *BlockList) IsBlocked(ip string) bool {
 	bl.mu.Lock()
 	defer bl.mu.Unlock()
 
-	return bl.blockedIPs[ip]
+	entry, ok := bl.blockedIPs[ip]
+	return ok && !entry.expired(time.Now())
 }
 
-// RemoveIP desbloquea una IP
-func (bl *BlockList) RemoveIP(ip string) {
+// RemoveIP desbloquea una IP. Retorna la entrada eliminada y si existia.
+func (bl *BlockList) RemoveIP(ip string) (BlockedIP, bool) {
 	bl.mu.Lock()
 	defer bl.mu.Unlock()
 
+	entry, ok := bl.blockedIPs[ip]
 	delete(bl.blockedIPs, ip)
+	return entry, ok
+}
+
+// RemoveExpired elimina los bloqueos vencidos y los retorna.
+func (bl *BlockList) RemoveExpired(now time.Time) []BlockedIP {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	var expired []BlockedIP
+	for ip, entry := range bl.blockedIPs {
+		if entry.expired(now) {
+			expired = append(expired, entry)
+			delete(bl.blockedIPs, ip)
+		}
+	}
+	return expired
 }
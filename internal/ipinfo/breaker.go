@@ -0,0 +1,268 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Valores por defecto de los circuit breakers.
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerOpenTimeout      = 30 * time.Second
+)
+
+// circuitBreaker corta las consultas a una operacion de MELI despues de FailureThreshold fallas
+// consecutivas. Abierto, falla de inmediato con ErrCircuitOpen; pasado OpenTimeout pasa a
+// semiabierto y deja pasar una unica consulta de prueba, que lo cierra si tiene exito o lo
+// vuelve a abrir si falla.
+type circuitBreaker struct {
+	name        string
+	threshold   int
+	openTimeout time.Duration
+	onOpen      func(name string, err error) // se llama cada vez que el breaker se abre
+
+	mu        sync.Mutex
+	state     models.BreakerState
+	failures  int // fallas consecutivas
+	openedAt  time.Time
+	probing   bool // hay una consulta de prueba en curso (semiabierto)
+	lastError string
+	now       func() time.Time
+}
+
+// newCircuitBreaker crea un breaker cerrado.
+func newCircuitBreaker(name string, threshold int, openTimeout time.Duration, onOpen func(name string, err error)) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerFailureThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = DefaultBreakerOpenTimeout
+	}
+	return &circuitBreaker{
+		name:        name,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		onOpen:      onOpen,
+		state:       models.BreakerClosed,
+		now:         time.Now,
+	}
+}
+
+// do ejecuta fn si el breaker lo permite y registra su resultado.
+func (b *circuitBreaker) do(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
+	probe, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+	val, err := fn()
+	b.record(ctx, probe, err)
+	return val, err
+}
+
+// allow indica si puede realizarse una consulta. probe es true si la consulta es la prueba del
+// estado semiabierto.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case models.BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false, b.openError()
+		}
+		b.state = models.BreakerHalfOpen
+		fallthrough
+	case models.BreakerHalfOpen:
+		if b.probing {
+			return false, b.openError()
+		}
+		b.probing = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// record actualiza el estado con el resultado de una consulta.
+func (b *circuitBreaker) record(ctx context.Context, probe bool, err error) {
+	b.mu.Lock()
+	if probe {
+		b.probing = false
+	}
+
+	// Las fallas que no son de MELI (solicitud cancelada por el cliente, recurso inexistente) no
+	// cuentan. Una solicitud que vencio su plazo esperando a MELI si cuenta
+	if err != nil && (callerCanceled(ctx, err) || !upstreamFailure(err)) {
+		b.mu.Unlock()
+		return
+	}
+
+	if err == nil {
+		if b.state != models.BreakerClosed {
+			log.Printf("[INFO] Circuit breaker %s cerrado", b.name)
+		}
+		b.state, b.failures, b.lastError = models.BreakerClosed, 0, ""
+		b.mu.Unlock()
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	opened := b.state == models.BreakerHalfOpen || b.failures >= b.threshold
+	if opened {
+		b.state, b.openedAt = models.BreakerOpen, b.now()
+		log.Printf("[WARN] Circuit breaker %s abierto tras %d fallas: %v", b.name, b.failures, err)
+	}
+	b.mu.Unlock()
+
+	if opened && b.onOpen != nil {
+		b.onOpen(b.name, err)
+	}
+}
+
+// openError es el error de una consulta rechazada por el breaker. Envuelve api.ErrUnavailable para
+// que se trate como cualquier otra indisponibilidad de MELI (respuesta parcial, 502).
+func (b *circuitBreaker) openError() error {
+	return fmt.Errorf("%s: %w: %w", b.name, ErrCircuitOpen, api.ErrUnavailable)
+}
+
+// status retorna el estado del breaker para el health check.
+func (b *circuitBreaker) status() models.BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := models.BreakerStatus{
+		Name:      b.name,
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if b.state != models.BreakerClosed {
+		openedAt := b.openedAt.UTC()
+		status.OpenedAt = &openedAt
+		if b.state == models.BreakerOpen {
+			retryAt := openedAt.Add(b.openTimeout)
+			status.RetryAt = &retryAt
+		}
+	}
+	return status
+}
+
+// callerCanceled indica si la consulta fallo porque quien la hizo la cancelo. Se usa la causa de
+// ctx: una consulta compartida que se cancela porque su ultimo interesado vencio su plazo tiene
+// causa context.DeadlineExceeded aunque ctx.Err() sea context.Canceled.
+func callerCanceled(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return errors.Is(context.Cause(ctx), context.Canceled)
+	}
+	return errors.Is(err, context.Canceled)
+}
+
+// upstreamFailure indica si err es una falla de MELI que debe contar para abrir el breaker.
+func upstreamFailure(err error) bool {
+	if errors.Is(err, api.ErrNotFound) {
+		return false
+	}
+	return errors.Is(err, api.ErrUnavailable) || errors.Is(err, api.ErrTimeout) ||
+		errors.Is(err, api.ErrRateLimited) || errors.Is(err, api.ErrBadResponse) || errors.Is(err, api.ErrUnauthorized)
+}
+
+////////////////////////////////
+// *** BREAKER REPOSITORY ***
+
+// breakerRepository envuelve un Repository con un circuit breaker por operacion de MELI. La
+// geolocalizacion (GetCountryByIP) es local y no pasa por ningun breaker.
+type breakerRepository struct {
+	Repository
+	breakers map[string]*circuitBreaker
+}
+
+// newBreakerRepository crea un breaker por operacion de MELI sobre r.
+func newBreakerRepository(r Repository, threshold int, openTimeout time.Duration, onOpen func(name string, err error)) *breakerRepository {
+	operations := []string{
+		api.OperationCountries, api.OperationCountry, api.OperationState,
+		api.OperationCity, api.OperationCurrencies, api.OperationCurrencyConversion,
+	}
+	breakers := make(map[string]*circuitBreaker, len(operations))
+	for _, operation := range operations {
+		breakers[operation] = newCircuitBreaker(operation, threshold, openTimeout, onOpen)
+	}
+	return &breakerRepository{Repository: r, breakers: breakers}
+}
+
+// statuses retorna el estado de todos los breakers ordenados por nombre.
+func (r *breakerRepository) statuses() []models.BreakerStatus {
+	statuses := make([]models.BreakerStatus, 0, len(r.breakers))
+	for _, breaker := range r.breakers {
+		statuses = append(statuses, breaker.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (r *breakerRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	val, err := r.breakers[api.OperationCountries].do(ctx, func() (interface{}, error) {
+		return r.Repository.FetchCountries(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]models.Country), nil
+}
+
+func (r *breakerRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	val, err := r.breakers[api.OperationCountry].do(ctx, func() (interface{}, error) {
+		return r.Repository.FetchCountryById(ctx, countryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CountryInfo), nil
+}
+
+func (r *breakerRepository) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	val, err := r.breakers[api.OperationState].do(ctx, func() (interface{}, error) {
+		return r.Repository.FetchStateById(ctx, stateID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.StateInfo), nil
+}
+
+func (r *breakerRepository) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	val, err := r.breakers[api.OperationCity].do(ctx, func() (interface{}, error) {
+		return r.Repository.FetchCityById(ctx, cityID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CityInfo), nil
+}
+
+func (r *breakerRepository) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
+	val, err := r.breakers[api.OperationCurrencies].do(ctx, func() (interface{}, error) {
+		return r.Repository.FetchCurrencies(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]models.Currency), nil
+}
+
+func (r *breakerRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	val, err := r.breakers[api.OperationCurrencyConversion].do(ctx, func() (interface{}, error) {
+		return r.Repository.FetchCurrencyConversion(ctx, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CurrencyExchange), nil
+}
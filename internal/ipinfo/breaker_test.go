@@ -0,0 +1,111 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"testing"
+	"time"
+)
+
+// newTestBreaker crea un breaker con umbral 2 y un reloj que solo avanza al modificar *now.
+func newTestBreaker(opened *int) (*circuitBreaker, *time.Time) {
+	now := time.Date(2024, 12, 20, 13, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker("country", 2, time.Minute, func(name string, err error) { *opened++ })
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+// callBreaker ejecuta una consulta por el breaker que retorna err.
+func callBreaker(b *circuitBreaker, ctx context.Context, err error) error {
+	_, got := b.do(ctx, func() (interface{}, error) { return nil, err })
+	return got
+}
+
+// TestBreakerTransitions verifica el ciclo cerrado -> abierto -> semiabierto -> cerrado/abierto.
+func TestBreakerTransitions(t *testing.T) {
+	var opened int
+	b, now := newTestBreaker(&opened)
+	ctx := context.Background()
+	errUpstream := fmt.Errorf("country: %w", api.ErrUnavailable)
+
+	// Cerrado: una falla no alcanza el umbral y un exito reinicia la cuenta
+	callBreaker(b, ctx, errUpstream)
+	callBreaker(b, ctx, nil)
+	callBreaker(b, ctx, errUpstream)
+	if state := b.status().State; state != models.BreakerClosed {
+		t.Fatalf("se esperaba el breaker cerrado, esta %s", state)
+	}
+
+	// Abierto: al llegar al umbral rechaza las consultas sin ejecutarlas
+	callBreaker(b, ctx, errUpstream)
+	if state := b.status().State; state != models.BreakerOpen || opened != 1 {
+		t.Fatalf("se esperaba el breaker abierto una vez, esta %s y se abrio %d veces", state, opened)
+	}
+	executed := false
+	if _, err := b.do(ctx, func() (interface{}, error) { executed = true; return nil, nil }); !errors.Is(err, ErrCircuitOpen) || executed {
+		t.Fatalf("se esperaba ErrCircuitOpen sin ejecutar la consulta, se obtuvo %v", err)
+	}
+
+	// Semiabierto: pasado OpenTimeout deja pasar una unica prueba; si falla vuelve a abrirse
+	*now = now.Add(time.Minute)
+	_, err := b.do(ctx, func() (interface{}, error) {
+		if state := b.status().State; state != models.BreakerHalfOpen {
+			t.Errorf("durante la prueba se esperaba el breaker semiabierto, esta %s", state)
+		}
+		if err := callBreaker(b, ctx, nil); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("se esperaba rechazar una segunda consulta durante la prueba, se obtuvo %v", err)
+		}
+		return nil, errUpstream
+	})
+	if !errors.Is(err, api.ErrUnavailable) || b.status().State != models.BreakerOpen || opened != 2 {
+		t.Fatalf("se esperaba reabrir el breaker tras la prueba fallida: %v, %s, %d", err, b.status().State, opened)
+	}
+	if err := callBreaker(b, ctx, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("se esperaba ErrCircuitOpen antes de OpenTimeout, se obtuvo %v", err)
+	}
+
+	// Una prueba exitosa lo cierra
+	*now = now.Add(time.Minute)
+	if err := callBreaker(b, ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if status := b.status(); status.State != models.BreakerClosed || status.Failures != 0 || status.OpenedAt != nil {
+		t.Fatalf("se esperaba el breaker cerrado y sin fallas: %+v", status)
+	}
+}
+
+// TestBreakerIgnoredFailures verifica que los recursos inexistentes y las consultas canceladas por
+// el cliente no cuentan como fallas, y que las que vencen su plazo si.
+func TestBreakerIgnoredFailures(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	// Consulta compartida cancelada porque su ultimo interesado vencio su plazo
+	abandoned, cancelAbandoned := context.WithCancelCause(context.Background())
+	cancelAbandoned(context.DeadlineExceeded)
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		err   error
+		count bool
+	}{
+		{"no encontrado", context.Background(), fmt.Errorf("country: %w", api.ErrNotFound), false},
+		{"cancelada por el cliente", canceled, fmt.Errorf("%w: %w", api.ErrUnavailable, context.Canceled), false},
+		{"plazo vencido", expired, fmt.Errorf("%w: %w", api.ErrTimeout, context.DeadlineExceeded), true},
+		{"compartida sin interesados por plazo", abandoned, fmt.Errorf("%w: %w", api.ErrUnavailable, context.Canceled), true},
+		{"no disponible", context.Background(), fmt.Errorf("country: %w", api.ErrUnavailable), true},
+	}
+	for _, tt := range tests {
+		var opened int
+		b, _ := newTestBreaker(&opened)
+		callBreaker(b, tt.ctx, tt.err)
+		if counted := b.status().Failures == 1; counted != tt.count {
+			t.Errorf("%s: se esperaba contar la falla = %v", tt.name, tt.count)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/cache"
+	"sync"
+	"time"
+)
+
+// Tiempos de vida por defecto de cada tipo de dato de referencia de MELI.
+const (
+	DefaultCountriesCacheTime  = 24 * time.Hour
+	DefaultCountryCacheTime    = 24 * time.Hour
+	DefaultLocationCacheTime   = 24 * time.Hour
+	DefaultCurrenciesCacheTime = 24 * time.Hour
+	DefaultRateCacheTime       = 5 * time.Minute
+	DefaultStaleCacheTime      = 24 * time.Hour
+)
+
+// DefaultRefreshTimeout es el plazo por defecto de cada consulta a MELI en segundo plano.
+const DefaultRefreshTimeout = 10 * time.Second
+
+// Claves de cache de los datos de referencia de MELI.
+const (
+	countriesCacheKey    = "countries:list"
+	countryCacheKey      = "country:"
+	currenciesCacheKey   = "currencies:list"
+	currencyRateCacheKey = "rate:"
+	stateCacheKey        = "state:"
+	cityCacheKey         = "city:"
+)
+
+// validUntilLayouts son los formatos en que MELI informa el vencimiento de una cotización.
+var validUntilLayouts = []string{"2006-01-02T15:04:05.000-0700", time.RFC3339Nano}
+
+// CacheTimes son los tiempos de vida de cada tipo de dato de referencia. Los valores cero toman
+// el valor por defecto.
+type CacheTimes struct {
+	Countries  time.Duration // lista de países
+	Country    time.Duration // detalle de un país
+	Location   time.Duration // detalle de estados y ciudades
+	Currencies time.Duration // lista de monedas
+	// Rate se usa para las cotizaciones que no informan valid_until o que ya vencieron.
+	Rate time.Duration
+	// Stale es el margen despues del vencimiento en el que un dato se sigue sirviendo, marcado
+	// como vencido, mientras se refresca o si MELI falla.
+	Stale time.Duration
+	// Refresh es el plazo de cada consulta a MELI en segundo plano para refrescar un dato vencido,
+	// que no depende del plazo de la solicitud que la disparo.
+	Refresh time.Duration
+}
+
+// cachedRepository envuelve un Repository con una caché por tipo de dato de referencia, independiente
+// de la caché por IP. Asi, una IP nueva de un país conocido no requiere consultas a MELI. Las
+// cotizaciones se guardan hasta su valid_until. Los errores no se guardan. Un dato vencido se
+// sigue sirviendo durante Stale mientras se refresca en segundo plano, y se informa con markStale.
+type cachedRepository struct {
+	Repository
+	cache *cache.Cache
+	times CacheTimes
+	now   func() time.Time
+}
+
+// newCachedRepository crea un repositorio con caché sobre r.
+func newCachedRepository(r Repository, times CacheTimes) *cachedRepository {
+	defaults := []struct {
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{&times.Countries, DefaultCountriesCacheTime},
+		{&times.Country, DefaultCountryCacheTime},
+		{&times.Location, DefaultLocationCacheTime},
+		{&times.Currencies, DefaultCurrenciesCacheTime},
+		{&times.Rate, DefaultRateCacheTime},
+		{&times.Stale, DefaultStaleCacheTime},
+		{&times.Refresh, DefaultRefreshTimeout},
+	}
+	for _, d := range defaults {
+		if *d.value <= 0 {
+			*d.value = d.fallback
+		}
+	}
+	c := cache.NewStaleCache(times.Country, times.Stale)
+	c.SetRevalidateTimeout(times.Refresh)
+	return &cachedRepository{
+		Repository: r,
+		cache:      c,
+		times:      times,
+		now:        time.Now,
+	}
+}
+
+// cached retorna el valor de key si esta en la caché, y si no lo obtiene con fn y lo guarda
+// por el tiempo que retorna ttl. Si el valor esta vencido se retorna igual, se refresca en
+// segundo plano y se registra source en ctx como dato vencido.
+func (r *cachedRepository) cached(ctx context.Context, source, key string, ttl func(val interface{}) time.Duration, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	val, stale, err := r.cache.Fetch(ctx, key, func(ctx context.Context) (interface{}, time.Duration, error) {
+		val, err := fn(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		return val, ttl(val), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		markStale(ctx, source)
+	}
+	return val, nil
+}
+
+// fixed retorna una funcion ttl que siempre retorna d.
+func fixed(d time.Duration) func(interface{}) time.Duration {
+	return func(interface{}) time.Duration { return d }
+}
+
+// rateTTL retorna el tiempo de vida de una cotización: hasta su valid_until, o Rate si MELI no
+// lo informa o ya vencio.
+func (r *cachedRepository) rateTTL(val interface{}) time.Duration {
+	exchange := val.(*models.CurrencyExchange)
+	for _, layout := range validUntilLayouts {
+		if validUntil, err := time.Parse(layout, exchange.ValidUntil); err == nil {
+			if ttl := validUntil.Sub(r.now()); ttl > 0 {
+				return ttl
+			}
+			break
+		}
+	}
+	return r.times.Rate
+}
+
+func (r *cachedRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	val, err := r.cached(ctx, "countries", countriesCacheKey, fixed(r.times.Countries), func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCountries(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]models.Country), nil
+}
+
+func (r *cachedRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	val, err := r.cached(ctx, "country", countryCacheKey+countryID, fixed(r.times.Country), func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCountryById(ctx, countryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CountryInfo), nil
+}
+
+func (r *cachedRepository) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	val, err := r.cached(ctx, "state", stateCacheKey+stateID, fixed(r.times.Location), func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchStateById(ctx, stateID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.StateInfo), nil
+}
+
+func (r *cachedRepository) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	val, err := r.cached(ctx, "city", cityCacheKey+cityID, fixed(r.times.Location), func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCityById(ctx, cityID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CityInfo), nil
+}
+
+func (r *cachedRepository) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
+	val, err := r.cached(ctx, "currencies", currenciesCacheKey, fixed(r.times.Currencies), func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCurrencies(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]models.Currency), nil
+}
+
+func (r *cachedRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	val, err := r.cached(ctx, "currency_conversion", currencyRateCacheKey+from+":"+to, r.rateTTL, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCurrencyConversion(ctx, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CurrencyExchange), nil
+}
+
+// staleKey es la clave de contexto del registro de datos vencidos.
+type staleKey struct{}
+
+// staleSources registra las fuentes de MELI cuyos datos se sirvieron vencidos de la caché durante
+// una consulta.
+type staleSources struct {
+	mu      sync.Mutex
+	sources []string
+}
+
+// withStaleTracking retorna un contexto en el que cachedRepository registra los datos vencidos
+// que sirve.
+func withStaleTracking(ctx context.Context) (context.Context, *staleSources) {
+	tracked := &staleSources{}
+	return context.WithValue(ctx, staleKey{}, tracked), tracked
+}
+
+// markStale registra source como vencida, si ctx tiene un registro.
+func markStale(ctx context.Context, source string) {
+	tracked, ok := ctx.Value(staleKey{}).(*staleSources)
+	if !ok {
+		return
+	}
+	tracked.mu.Lock()
+	defer tracked.mu.Unlock()
+	for _, s := range tracked.sources {
+		if s == source {
+			return
+		}
+	}
+	tracked.sources = append(tracked.sources, source)
+}
+
+// list retorna las fuentes registradas.
+func (t *staleSources) list() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.sources...)
+}
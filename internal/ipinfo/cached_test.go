@@ -0,0 +1,106 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"testing"
+	"time"
+)
+
+// TestColdIPKnownCountry verifica que una IP que no esta en la caché, de un país ya consultado,
+// solo requiere la geolocalizacion: países, país y cotización salen de la caché de referencia.
+func TestColdIPKnownCountry(t *testing.T) {
+	repo := &countingRepository{}
+	s := newBenchmarkService(repo, false)
+	s.r = newCachedRepository(repo, CacheTimes{})
+	ctx := context.Background()
+
+	if _, err := s.GetCountryDataByIP(ctx, "200.0.0.1", LookupOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	before := repo.calls.Load()
+
+	info, err := s.GetCountryDataByIP(ctx, "200.0.0.2", LookupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Partial || info.CurrencyConversionToUSD == nil {
+		t.Fatalf("se esperaba una respuesta completa, se obtuvo %+v", info)
+	}
+	// La unica consulta es la geolocalizacion de la IP nueva
+	if calls := repo.calls.Load() - before; calls != 1 {
+		t.Fatalf("se esperaba 1 consulta (geolocalizacion), se hicieron %d", calls)
+	}
+}
+
+// TestRateTTL verifica que las cotizaciones se guardan hasta su valid_until.
+func TestRateTTL(t *testing.T) {
+	now := time.Date(2024, 12, 20, 13, 0, 0, 0, time.UTC)
+	r := newCachedRepository(&countingRepository{}, CacheTimes{Rate: time.Minute})
+	r.now = func() time.Time { return now }
+
+	tests := []struct {
+		validUntil string
+		want       time.Duration
+	}{
+		{"2024-12-20T13:20:00.000+0000", 20 * time.Minute},
+		{"2024-12-20T10:30:00-03:00", 30 * time.Minute},
+		{"2024-12-20T12:00:00.000+0000", time.Minute}, // vencida
+		{"", time.Minute},
+	}
+	for _, tt := range tests {
+		if got := r.rateTTL(&models.CurrencyExchange{ValidUntil: tt.validUntil}); got != tt.want {
+			t.Errorf("rateTTL(%q) = %v, se esperaba %v", tt.validUntil, got, tt.want)
+		}
+	}
+}
+
+// TestStaleReferenceData verifica que un dato de referencia vencido se sirve sin esperar a MELI y
+// que la respuesta lo informa.
+func TestStaleReferenceData(t *testing.T) {
+	repo := &countingRepository{}
+	s := newBenchmarkService(repo, false)
+	r := newCachedRepository(repo, CacheTimes{})
+	s.r = r
+	ctx := context.Background()
+
+	if _, err := s.GetCountryDataByIP(ctx, "200.0.0.1", LookupOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	item, _ := r.cache.Peek(countryCacheKey + "AR")
+	r.cache.SetWithTTL(countryCacheKey+"AR", item.Data, -time.Second)
+
+	info, err := s.GetCountryDataByIP(ctx, "200.0.0.2", LookupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Stale || len(info.StaleSources) != 1 || info.StaleSources[0] != "country" {
+		t.Fatalf("se esperaba el país marcado como vencido, se obtuvo stale=%v %v", info.Stale, info.StaleSources)
+	}
+	if info.Partial || info.CurrencyConversionToUSD == nil {
+		t.Fatalf("se esperaba una respuesta completa, se obtuvo %+v", info)
+	}
+}
+
+// TestStaleReferenceDataBatch verifica que en un lote todas las IPs que usan un dato vencido lo
+// informan, no solo la que lo obtuvo de la caché.
+func TestStaleReferenceDataBatch(t *testing.T) {
+	repo := &countingRepository{latency: 20 * time.Millisecond}
+	s := newBenchmarkService(repo, false)
+	r := newCachedRepository(repo, CacheTimes{})
+	s.r = r
+	ctx := context.Background()
+
+	if _, err := s.GetCountryDataByIP(ctx, "200.0.0.1", LookupOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	item, _ := r.cache.Peek(countryCacheKey + "AR")
+	r.cache.SetWithTTL(countryCacheKey+"AR", item.Data, -time.Second)
+
+	ips := []string{"200.0.0.2", "200.0.0.3", "200.0.0.4"}
+	for result := range s.LookupBatch(ctx, ips, len(ips), LookupOptions{}) {
+		if result.Data == nil || !result.Data.Stale {
+			t.Errorf("%s: se esperaba el resultado marcado como vencido, se obtuvo %+v", result.IP, result)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package ipinfo
+
+import "errors"
+
+// Errores del servicio. Los errores de las capas inferiores (pkg/api y pkg/store)
+// se envuelven con %w, por lo que tambien pueden compararse con errors.Is.
+var (
+	// ErrCountryNotOperated indica que el país de la IP no opera MercadoLibre.
+	ErrCountryNotOperated = errors.New("el país no opera MercadoLibre")
+	// ErrCountryNotFound indica que el país solicitado no existe en MercadoLibre.
+	ErrCountryNotFound = errors.New("país no encontrado")
+	// ErrLocationNotFound indica que el estado o la ciudad solicitados no existen en MercadoLibre.
+	ErrLocationNotFound = errors.New("ubicación no encontrada")
+	// ErrUnknownCurrency indica que la moneda solicitada no existe en MercadoLibre.
+	ErrUnknownCurrency = errors.New("moneda desconocida")
+	// ErrSpecialPurposeAddress indica que la IP es de proposito especial (privada, loopback, documentacion, etc.).
+	// El detalle del rango se obtiene con errors.As sobre *SpecialPurposeError.
+	ErrSpecialPurposeAddress = errors.New("dirección de propósito especial")
+	// ErrCircuitOpen indica que el circuit breaker de la operacion esta abierto y la consulta no se
+	// realizo. Tambien se compara como api.ErrUnavailable.
+	ErrCircuitOpen = errors.New("circuit breaker abierto")
+)
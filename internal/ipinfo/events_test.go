@@ -0,0 +1,185 @@
+package ipinfo
+
+import (
+	"encoding/json"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newEventsService crea un servicio que guarda la lista de bloqueos en un directorio temporal.
+func newEventsService(t *testing.T) *service {
+	t.Helper()
+	s := newBenchmarkService(&countingRepository{}, false)
+	s.breakers = newBreakerRepository(s.r, 5, time.Minute, s.reportBreakerOpen)
+	s.filePath = filepath.Join(t.TempDir(), "blocked.json")
+	return s
+}
+
+// nextEvent espera el proximo evento de events.
+func nextEvent(t *testing.T, events chan models.Event) models.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no se recibio el evento")
+		return models.Event{}
+	}
+}
+
+// TestBlockEvents verifica el payload de los eventos BLOCKED, UNBLOCKED y LOOKUP_DENIED y que la
+// lista de bloqueos se guarda en el archivo.
+func TestBlockEvents(t *testing.T) {
+	s := newEventsService(t)
+	events := s.SubscribeEvents()
+	defer s.UnsubscribeEvents(events)
+
+	before := time.Now()
+	if err := s.BlockIP("1.1.1.1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	event := nextEvent(t, events)
+	blocked, ok := event.Payload.(models.BlockedPayload)
+	if event.Event != models.EventBlocked || event.IP != "1.1.1.1" || !ok || blocked.ExpiresAt == nil || blocked.ExpiresAt.Sub(before) < time.Hour || event.Timestamp.IsZero() {
+		t.Fatalf("evento BLOCKED inesperado: %+v", event)
+	}
+	if err := s.BlockIP("2.2.2.2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Payload.(models.BlockedPayload).ExpiresAt != nil {
+		t.Fatalf("un bloqueo permanente no deberia vencer: %+v", event)
+	}
+
+	var saved []BlockedIP
+	if data, err := os.ReadFile(s.filePath); err != nil || json.Unmarshal(data, &saved) != nil || len(saved) != 2 {
+		t.Fatalf("lista guardada inesperada: %s, %v", data, err)
+	}
+	if _, err := os.Stat(s.filePath + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("quedo el archivo temporal: %v", err)
+	}
+
+	s.ReportLookupDenied("2.2.2.2", models.LookupDeniedPayload{ClientIP: "203.0.113.7", UserAgent: "curl/8.0"})
+	event = nextEvent(t, events)
+	if denied, ok := event.Payload.(models.LookupDeniedPayload); event.Event != models.EventLookupDenied || event.IP != "2.2.2.2" || !ok || denied.ClientIP != "203.0.113.7" || denied.UserAgent != "curl/8.0" {
+		t.Fatalf("evento LOOKUP_DENIED inesperado: %+v", event)
+	}
+
+	blockedAt := s.BlockedIPs()[0].BlockedAt
+	if removed, err := s.UnblockIP("1.1.1.1"); !removed || err != nil {
+		t.Fatalf("UnblockIP() = %v, %v", removed, err)
+	}
+	event = nextEvent(t, events)
+	if unblocked, ok := event.Payload.(models.UnblockedPayload); event.Event != models.EventUnblocked || event.IP != "1.1.1.1" || !ok || !unblocked.BlockedAt.Equal(blockedAt) {
+		t.Fatalf("evento UNBLOCKED inesperado: %+v", event)
+	}
+	if removed, _ := s.UnblockIP("1.1.1.1"); removed || len(events) != 0 {
+		t.Fatal("desbloquear una IP no bloqueada no deberia emitir eventos")
+	}
+}
+
+// TestExpiredEvents verifica que el barrido elimina los bloqueos vencidos en el instante recibido
+// y emite EXPIRED con su payload.
+func TestExpiredEvents(t *testing.T) {
+	s := newEventsService(t)
+	events := s.SubscribeEvents()
+	defer s.UnsubscribeEvents(events)
+
+	if err := s.BlockIP("1.1.1.1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BlockIP("2.2.2.2", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, events)
+	nextEvent(t, events)
+	entry := s.BlockedIPs()[0]
+
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		s.sweepExpiredBlocks(ticks)
+		close(done)
+	}()
+	ticks <- time.Now().Add(30 * time.Second) // ninguno vencio
+	ticks <- time.Now().Add(2 * time.Minute)
+	close(ticks)
+	<-done
+
+	event := nextEvent(t, events)
+	expired, ok := event.Payload.(models.ExpiredPayload)
+	if event.Event != models.EventExpired || event.IP != "1.1.1.1" || !ok || !expired.BlockedAt.Equal(entry.BlockedAt) || !expired.ExpiredAt.Equal(*entry.ExpiresAt) {
+		t.Fatalf("evento EXPIRED inesperado: %+v", event)
+	}
+	if len(events) != 0 || s.IsBlocked("1.1.1.1") || !s.IsBlocked("2.2.2.2") {
+		t.Fatalf("solo deberia vencer 1.1.1.1 (%d eventos pendientes)", len(events))
+	}
+	var saved []BlockedIP
+	if data, _ := os.ReadFile(s.filePath); json.Unmarshal(data, &saved) != nil || len(saved) != 1 || saved[0].IP != "2.2.2.2" {
+		t.Fatalf("lista guardada inesperada: %+v", saved)
+	}
+}
+
+// TestSlowSubscriber verifica que un suscriptor que no recibe los eventos a tiempo se desconecta,
+// despues de los eventos de su buffer, sin afectar a los demas, y que se cuenta en Health.
+func TestSlowSubscriber(t *testing.T) {
+	s := newEventsService(t)
+	slow := s.SubscribeEvents()
+	fast := s.SubscribeEvents()
+	defer s.UnsubscribeEvents(fast)
+
+	for i := 0; i <= BufferSizeClients; i++ {
+		s.ReportLookupDenied("1.1.1.1", models.LookupDeniedPayload{})
+		nextEvent(t, fast)
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != BufferSizeClients {
+		t.Fatalf("el suscriptor lento recibio %d eventos antes de desconectarse, se esperaban %d", received, BufferSizeClients)
+	}
+	s.UnsubscribeEvents(slow) // ya desconectado: no debe cerrar el canal de nuevo
+
+	if events := s.Health().Events; events.Subscribers != 1 || events.Disconnected != 1 || events.Dropped != 1 {
+		t.Fatalf("estado de eventos inesperado: %+v", events)
+	}
+}
+
+// TestStateReloadedReplay verifica que el evento STATE_RELOADED, emitido al cargar el estado antes
+// de que haya suscriptores, se entrega una unica vez a cada suscriptor, anterior o posterior.
+func TestStateReloadedReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "blocked.json")
+	content := `[{"ip":"1.1.1.1","blocked_at":"2024-12-20T13:00:00Z"},{"ip":"2.2.2.2","blocked_at":"2024-12-20T13:00:00Z"}]`
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newBenchmarkService(&countingRepository{}, false)
+	s.filePath = file
+
+	before := s.SubscribeEvents()
+	if len(before) != 0 {
+		t.Fatalf("antes de cargar el estado no deberia haber eventos, hay %d", len(before))
+	}
+	s.reloadState()
+	after := s.SubscribeEvents()
+	if !s.IsBlocked("2.2.2.2") {
+		t.Fatal("no se cargo la lista de bloqueos")
+	}
+
+	for name, events := range map[string]chan models.Event{"anterior": before, "posterior": after} {
+		if len(events) != 1 {
+			t.Fatalf("suscriptor %s: se esperaba un evento, hay %d", name, len(events))
+		}
+		event := <-events
+		payload, ok := event.Payload.(models.StateReloadedPayload)
+		if event.Event != models.EventStateReloaded || !ok || payload.BlockedIPs != 2 || payload.Source != file || event.Timestamp.IsZero() {
+			t.Errorf("suscriptor %s: evento inesperado %+v", name, event)
+		}
+	}
+	s.UnsubscribeEvents(before)
+	s.UnsubscribeEvents(after)
+}
@@ -0,0 +1,169 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"sync"
+)
+
+// flightGroup coalesce las llamadas concurrentes con la misma clave: mientras una esta en curso,
+// las demas esperan su resultado en lugar de repetirla. A diferencia de sharedRepository el
+// resultado no se conserva: la clave se libera apenas termina la llamada.
+//
+// La llamada no depende del contexto de quien la inicio, para que una solicitud cancelada no haga
+// fallar a las que esperan el mismo resultado: cada llamador deja de esperar cuando vence su propio
+// contexto, y la llamada se cancela cuando deja de esperarla el ultimo, con la causa de su contexto
+// (context.Canceled o context.DeadlineExceeded). Un flightGroup nil ejecuta cada llamada sin coalescer.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall es una llamada en curso de un flightGroup.
+type flightCall struct {
+	sharedCall
+	cancel  context.CancelCauseFunc
+	waiters int // llamadores que esperan el resultado
+}
+
+// newFlightGroup crea un grupo sin llamadas en curso.
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do ejecuta fn una unica vez por clave entre las llamadas concurrentes y espera su resultado
+// o el fin de ctx.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if g == nil {
+		return fn(ctx)
+	}
+
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+		call = &flightCall{sharedCall: sharedCall{done: make(chan struct{})}, cancel: cancel}
+		g.calls[key] = call
+		go func() {
+			call.val, call.err = fn(callCtx)
+			g.release(key, call)
+			cancel(nil)
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		g.leave(key, call, context.Cause(ctx))
+		return nil, ctx.Err()
+	}
+}
+
+// leave registra que un llamador dejo de esperar call. Si era el ultimo, cancela la llamada con
+// cause y libera la clave para que una llamada posterior no reciba la cancelacion.
+func (g *flightGroup) leave(key string, call *flightCall, cause error) {
+	g.mu.Lock()
+	call.waiters--
+	last := call.waiters == 0
+	if last {
+		g.releaseLocked(key, call)
+	}
+	g.mu.Unlock()
+
+	if last {
+		call.cancel(cause)
+	}
+}
+
+// release libera la clave de call, si todavia le corresponde.
+func (g *flightGroup) release(key string, call *flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.releaseLocked(key, call)
+}
+
+// releaseLocked es release con g.mu tomado.
+func (g *flightGroup) releaseLocked(key string, call *flightCall) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+////////////////////////////////
+// *** FLIGHT REPOSITORY ***
+
+// flightRepository envuelve un Repository y coalesce las consultas concurrentes a MELI con los
+// mismos parametros, aunque provengan de solicitudes distintas.
+type flightRepository struct {
+	Repository
+	flights *flightGroup
+}
+
+// newFlightRepository crea un repositorio que coalesce las consultas sobre r.
+func newFlightRepository(r Repository) *flightRepository {
+	return &flightRepository{Repository: r, flights: newFlightGroup()}
+}
+
+func (r *flightRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	val, err := r.flights.do(ctx, "countries", func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCountries(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]models.Country), nil
+}
+
+func (r *flightRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	val, err := r.flights.do(ctx, "country:"+countryID, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCountryById(ctx, countryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CountryInfo), nil
+}
+
+func (r *flightRepository) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	val, err := r.flights.do(ctx, "state:"+stateID, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchStateById(ctx, stateID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.StateInfo), nil
+}
+
+func (r *flightRepository) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	val, err := r.flights.do(ctx, "city:"+cityID, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCityById(ctx, cityID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CityInfo), nil
+}
+
+func (r *flightRepository) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
+	val, err := r.flights.do(ctx, "currencies", func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCurrencies(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]models.Currency), nil
+}
+
+func (r *flightRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	val, err := r.flights.do(ctx, "currency:"+from+":"+to, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCurrencyConversion(ctx, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CurrencyExchange), nil
+}
@@ -0,0 +1,188 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"github.com/AleHts29/meli-challenge/pkg/cache"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingRepository simula las APIs de MELI e IP2Location con una latencia fija y cuenta las
+// consultas. Mientras failing sea true todas fallan con api.ErrUnavailable.
+type countingRepository struct {
+	latency time.Duration
+	calls   atomic.Int64
+	failing atomic.Bool
+}
+
+func (r *countingRepository) call(ctx context.Context) error {
+	r.calls.Add(1)
+	if r.failing.Load() {
+		return api.ErrUnavailable
+	}
+	return sleepContext(ctx, r.latency)
+}
+
+func (r *countingRepository) GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	return &models.IPInfo{IP: ip, CountryCode: "AR", CountryName: "Argentina"}, nil
+}
+
+func (r *countingRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	return []models.Country{{ID: "AR", Name: "Argentina"}}, nil
+}
+
+func (r *countingRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	return &models.CountryInfo{Country: models.Country{ID: countryID, Name: "Argentina", CurrencyId: "ARS"}}, nil
+}
+
+func (r *countingRepository) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	return nil, r.call(ctx)
+}
+
+func (r *countingRepository) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	return nil, r.call(ctx)
+}
+
+func (r *countingRepository) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
+	return nil, r.call(ctx)
+}
+
+func (r *countingRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	return &models.CurrencyExchange{Rate: 0.001}, nil
+}
+
+// sleepContext espera d o hasta que ctx termine.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newBenchmarkService crea un servicio sobre r, con o sin coalescencia de consultas.
+func newBenchmarkService(r Repository, coalesce bool) *service {
+	s := &service{
+		r:         r,
+		blockList: NewBlockList(),
+		cache:     cache.NewCache(CacheTime),
+		stats:     newUsageStats(models.Location{}),
+		clients:   make(map[chan models.Event]struct{}),
+	}
+	if coalesce {
+		s.lookups = newFlightGroup()
+		s.r = newFlightRepository(r)
+	}
+	return s
+}
+
+// BenchmarkConcurrentLookups resuelve, en cada iteracion, una rafaga de consultas concurrentes
+// de una IP que no esta en la caché. upstream-calls/op es la cantidad de consultas a IP2Location
+// y MELI por rafaga: 4 por consulta sin coalescer y 4 en total coalesciendo.
+func BenchmarkConcurrentLookups(b *testing.B) {
+	for _, burst := range []int{10, 100} {
+		for _, coalesce := range []bool{false, true} {
+			b.Run(fmt.Sprintf("burst=%d/coalesce=%t", burst, coalesce), func(b *testing.B) {
+				repo := &countingRepository{latency: time.Millisecond}
+				s := newBenchmarkService(repo, coalesce)
+				ctx := context.Background()
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					// Una IP distinta por iteracion para que no este en la caché
+					ip := fmt.Sprintf("200.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)
+
+					var wg sync.WaitGroup
+					for j := 0; j < burst; j++ {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							if _, err := s.GetCountryDataByIP(ctx, ip, LookupOptions{}); err != nil {
+								b.Error(err)
+							}
+						}()
+					}
+					wg.Wait()
+				}
+				b.ReportMetric(float64(repo.calls.Load())/float64(b.N), "upstream-calls/op")
+			})
+		}
+	}
+}
+
+// TestFlightCancel verifica que la llamada compartida se cancela cuando deja de esperarla el
+// ultimo llamador, con la causa de su contexto, y no antes.
+func TestFlightCancel(t *testing.T) {
+	g := newFlightGroup()
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			stopped <- context.Cause(ctx)
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			stopped <- nil
+			return "ok", nil
+		}
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelSecond()
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.do(first, "k", fn)
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := g.do(second, "k", fn)
+		errs <- err
+	}()
+
+	// Sigue esperando el segundo llamador: la llamada no se cancela
+	time.Sleep(10 * time.Millisecond)
+	cancelFirst()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("se esperaba context.Canceled para el primer llamador, se obtuvo %v", err)
+	}
+	select {
+	case cause := <-stopped:
+		t.Fatalf("la llamada termino con un llamador esperando: %v", cause)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Al vencer el plazo del ultimo llamador la llamada se cancela con esa causa
+	if err := <-errs; err != context.DeadlineExceeded {
+		t.Fatalf("se esperaba context.DeadlineExceeded para el segundo llamador, se obtuvo %v", err)
+	}
+	select {
+	case cause := <-stopped:
+		if cause != context.DeadlineExceeded {
+			t.Fatalf("se esperaba la causa context.DeadlineExceeded, se obtuvo %v", cause)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("la llamada no se cancelo al irse el ultimo llamador")
+	}
+}
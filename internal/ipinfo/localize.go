@@ -0,0 +1,135 @@
+package ipinfo
+
+import (
+	"context"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SampleAmount es el monto de ejemplo que se formatea con los separadores del país.
+const SampleAmount = 1234567.89
+
+// localize agrega a una copia de countryInfo la hora local actual del país y el
+// monto de ejemplo formateado con sus separadores y simbolo de moneda. Si no se pudo obtener
+// la lista de monedas, la copia se marca como parcial con un aviso de la fuente "currencies";
+// en modo estricto, que no admite respuestas parciales, solo se omiten los montos formateados.
+func (s *service) localize(ctx context.Context, countryInfo *models.CountryInfo, opts LookupOptions) *models.CountryInfo {
+	localized := &models.LocalizedInfo{}
+	var currenciesErr error
+
+	if location, err := parseTimeZone(countryInfo.TimeZone); err == nil {
+		now := time.Now().In(location)
+		localized.LocalTime = now.Format(time.RFC3339)
+		localized.LocalTimeFormatted = now.Format("02/01/2006 15:04:05")
+	}
+
+	// Sin separadores (respuesta parcial) no tiene sentido formatear montos
+	if countryInfo.DecimalSeparator != "" && countryInfo.CurrencyId != "" {
+		if currencies, err := s.currencies(ctx); err != nil {
+			if !opts.Strict {
+				currenciesErr = err
+			}
+		} else {
+			local := currencies[countryInfo.CurrencyId]
+			localized.SampleAmount = formatAmount(SampleAmount, local, countryInfo.DecimalSeparator, countryInfo.ThousandsSeparator)
+
+			if conversion := countryInfo.CurrencyConversionToUSD; conversion != nil {
+				usd, ok := currencies[BaseCurrency]
+				if !ok {
+					usd = models.Currency{ID: BaseCurrency, Symbol: "US$", DecimalPlaces: 2}
+				}
+				localized.SampleAmountUSD = formatAmount(SampleAmount*conversion.Rate, usd, countryInfo.DecimalSeparator, countryInfo.ThousandsSeparator)
+			}
+		}
+	}
+
+	if *localized == (models.LocalizedInfo{}) && currenciesErr == nil {
+		return countryInfo
+	}
+
+	withLocalized := *countryInfo
+	if *localized != (models.LocalizedInfo{}) {
+		withLocalized.Localized = localized
+	}
+	if currenciesErr != nil {
+		// Se copian los avisos para no modificar los de la informacion guardada en la caché
+		withLocalized.Warnings = append([]models.Warning(nil), countryInfo.Warnings...)
+		withLocalized.AddWarning("currencies", currenciesErr)
+	}
+	return &withLocalized
+}
+
+// parseTimeZone interpreta la zona horaria de MELI, que puede ser un nombre IANA
+// ("America/Argentina/Buenos_Aires") o un desplazamiento ("GMT-03:00", "UTC+05:30").
+func parseTimeZone(zone string) (*time.Location, error) {
+	zone = strings.TrimSpace(zone)
+	if zone == "" {
+		return nil, fmt.Errorf("zona horaria vacía")
+	}
+	if location, err := time.LoadLocation(zone); err == nil {
+		return location, nil
+	}
+
+	offset := strings.TrimPrefix(strings.TrimPrefix(zone, "GMT"), "UTC")
+	if offset == "" {
+		return time.UTC, nil
+	}
+	sign := 1
+	switch offset[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return nil, fmt.Errorf("zona horaria '%s' no reconocida", zone)
+	}
+
+	hoursPart, minutesPart, _ := strings.Cut(offset[1:], ":")
+	hours, err := strconv.Atoi(hoursPart)
+	if err != nil {
+		return nil, fmt.Errorf("zona horaria '%s' no reconocida", zone)
+	}
+	minutes := 0
+	if minutesPart != "" {
+		if minutes, err = strconv.Atoi(minutesPart); err != nil {
+			return nil, fmt.Errorf("zona horaria '%s' no reconocida", zone)
+		}
+	}
+	return time.FixedZone(zone, sign*(hours*3600+minutes*60)), nil
+}
+
+// formatAmount formatea un monto con los decimales y el simbolo de la moneda y los separadores indicados.
+// Ej: 1234567.89 con "," y "." -> "$ 1.234.567,89".
+func formatAmount(value float64, currency models.Currency, decimalSeparator, thousandsSeparator string) string {
+	decimals := currency.DecimalPlaces
+	if decimals < 0 {
+		decimals = 0
+	}
+
+	negative := value < 0
+	digits := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(digits, ".")
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(thousandsSeparator)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	amount := grouped.String()
+	if fraction != "" {
+		amount += decimalSeparator + fraction
+	}
+	if negative {
+		amount = "-" + amount
+	}
+	if currency.Symbol == "" {
+		return amount
+	}
+	return currency.Symbol + " " + amount
+}
@@ -0,0 +1,58 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"testing"
+)
+
+// currenciesRepository responde la lista de monedas, o api.ErrUnavailable si failing es true.
+type currenciesRepository struct {
+	countingRepository
+}
+
+func (r *currenciesRepository) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	return []models.Currency{{ID: "ARS", Symbol: "$", DecimalPlaces: 2}, {ID: "USD", Symbol: "U$S", DecimalPlaces: 2}}, nil
+}
+
+// TestLocalize verifica el formato del monto de ejemplo y que, si falla la lista de monedas, la
+// respuesta se marca como parcial con un aviso sin modificar la informacion original, salvo en
+// modo estricto.
+func TestLocalize(t *testing.T) {
+	repo := &currenciesRepository{}
+	s := newBenchmarkService(repo, false)
+	info := &models.CountryInfo{
+		Country:                 models.Country{ID: "AR", CurrencyId: "ARS"},
+		DecimalSeparator:        ",",
+		ThousandsSeparator:      ".",
+		CurrencyConversionToUSD: &models.CurrencyExchange{Rate: 0.001},
+	}
+
+	localized := s.localize(context.Background(), info, LookupOptions{})
+	if localized.Localized == nil || localized.Localized.SampleAmount != "$ 1.234.567,89" || localized.Localized.SampleAmountUSD != "U$S 1.234,57" || localized.Partial {
+		t.Fatalf("localizacion inesperada: %+v", localized.Localized)
+	}
+
+	// Con capacidad de sobra, un append sobre los avisos originales los modificaria
+	repo.failing.Store(true)
+	info.Warnings = make([]models.Warning, 1, 4)
+	info.Warnings[0] = models.Warning{Source: "state", Error: api.ErrTimeout.Error(), Err: api.ErrTimeout}
+	localized = s.localize(context.Background(), info, LookupOptions{})
+	if !localized.Partial || len(localized.Warnings) != 2 || localized.Warnings[1].Source != "currencies" || localized.Localized != nil {
+		t.Fatalf("se esperaba un aviso de currencies, se obtuvo %+v", localized)
+	}
+	if info.Partial || len(info.Warnings) != 1 || info.Warnings[:2][1].Source != "" {
+		t.Fatalf("se modifico la informacion original: %+v", info)
+	}
+
+	// En modo estricto no se marca como parcial: solo se omiten los montos formateados
+	info.TimeZone = "America/Argentina/Buenos_Aires"
+	localized = s.localize(context.Background(), info, LookupOptions{Strict: true})
+	if localized.Partial || len(localized.Warnings) != 1 || localized.Localized == nil || localized.Localized.LocalTime == "" || localized.Localized.SampleAmount != "" {
+		t.Fatalf("en modo estricto se esperaba la hora local sin montos ni avisos, se obtuvo %+v", localized)
+	}
+}
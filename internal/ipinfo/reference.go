@@ -0,0 +1,114 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+)
+
+////////////////////////////////
+// *** DATOS DE REFERENCIA ***
+
+// Los datos de referencia se guardan en la caché del repositorio (cachedRepository), que
+// comparten con la resolucion de IPs.
+
+// Countries retorna la lista de países en los que opera MELI.
+func (s *service) Countries(ctx context.Context) ([]models.Country, error) {
+	countries, err := s.r.FetchCountries(ctx)
+	if err != nil {
+		s.reportUpstreamFailure("countries", err)
+		return nil, fmt.Errorf("error al obtener la lista de países: %w", err)
+	}
+	return countries, nil
+}
+
+// Country retorna el detalle de un país, incluyendo sus estados.
+func (s *service) Country(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	ctx, stale := withStaleTracking(ctx)
+	country, err := s.r.FetchCountryById(ctx, countryID)
+	if err != nil {
+		if errors.Is(err, api.ErrNotFound) {
+			return nil, fmt.Errorf("'%s': %w", countryID, ErrCountryNotFound)
+		}
+		s.reportUpstreamFailure("country", err)
+		return nil, fmt.Errorf("error al obtener información del país: %w", err)
+	}
+
+	// Se copia el país para no modificar el de la caché
+	withDistance := *country
+	withDistance.DistanceKm = s.stats.distanceTo(country.GeoInformation)
+	withDistance.MarkStale(stale.list())
+	return &withDistance, nil
+}
+
+// State retorna el detalle de un estado, incluyendo sus ciudades.
+func (s *service) State(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	state, err := s.r.FetchStateById(ctx, stateID)
+	if err != nil {
+		if errors.Is(err, api.ErrNotFound) {
+			return nil, fmt.Errorf("estado '%s': %w", stateID, ErrLocationNotFound)
+		}
+		s.reportUpstreamFailure("state", err)
+		return nil, fmt.Errorf("error al obtener información del estado: %w", err)
+	}
+	return state, nil
+}
+
+// City retorna el detalle de una ciudad, incluyendo sus barrios.
+func (s *service) City(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	city, err := s.r.FetchCityById(ctx, cityID)
+	if err != nil {
+		if errors.Is(err, api.ErrNotFound) {
+			return nil, fmt.Errorf("ciudad '%s': %w", cityID, ErrLocationNotFound)
+		}
+		s.reportUpstreamFailure("city", err)
+		return nil, fmt.Errorf("error al obtener información de la ciudad: %w", err)
+	}
+	return city, nil
+}
+
+// Currencies retorna la lista de monedas de MELI.
+func (s *service) Currencies(ctx context.Context) ([]models.Currency, error) {
+	currencies, err := s.r.FetchCurrencies(ctx)
+	if err != nil {
+		s.reportUpstreamFailure("currencies", err)
+		return nil, fmt.Errorf("error al obtener la lista de monedas: %w", err)
+	}
+	return currencies, nil
+}
+
+// CurrencyRate retorna la cotización entre dos monedas de MELI.
+func (s *service) CurrencyRate(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	currencies, err := s.currencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, currency := range []string{from, to} {
+		if _, ok := currencies[currency]; !ok {
+			return nil, fmt.Errorf("'%s': %w", currency, ErrUnknownCurrency)
+		}
+	}
+
+	exchange, err := s.r.FetchCurrencyConversion(ctx, from, to)
+	if err != nil {
+		s.reportUpstreamFailure("currency_conversion", err)
+		return nil, fmt.Errorf("error al obtener la cotización de %s a %s: %w", from, to, err)
+	}
+	return exchange, nil
+}
+
+// currencies retorna las monedas de MELI indexadas por ID.
+func (s *service) currencies(ctx context.Context) (map[string]models.Currency, error) {
+	list, err := s.Currencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := make(map[string]models.Currency, len(list))
+	for _, currency := range list {
+		currencies[currency.ID] = currency
+	}
+	return currencies, nil
+}
@@ -0,0 +1,112 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"testing"
+)
+
+// referenceRepository responde como currenciesRepository, salvo los IDs que MELI no conoce, que
+// fallan con api.ErrNotFound como la API real ante un 404.
+type referenceRepository struct {
+	currenciesRepository
+}
+
+func (r *referenceRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	if countryID != "AR" {
+		return nil, fmt.Errorf("country %s: %w", countryID, api.ErrNotFound)
+	}
+	return r.countingRepository.FetchCountryById(ctx, countryID)
+}
+
+func (r *referenceRepository) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	if stateID != "AR-C" {
+		return nil, fmt.Errorf("state %s: %w", stateID, api.ErrNotFound)
+	}
+	return &models.StateInfo{ID: stateID, Name: "Capital Federal", Cities: []models.LocationRef{{ID: "TUxBQ1BBTDI1MTVh", Name: "Palermo"}}}, nil
+}
+
+func (r *referenceRepository) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	if cityID != "TUxBQ1BBTDI1MTVh" {
+		return nil, fmt.Errorf("city %s: %w", cityID, api.ErrNotFound)
+	}
+	return &models.CityInfo{ID: cityID, Name: "Palermo", State: models.LocationRef{ID: "AR-C", Name: "Capital Federal"}}, nil
+}
+
+// TestCountry verifica el detalle de un país y que un país desconocido para MELI se informe con
+// ErrCountryNotFound, mientras que las demas fallas conservan el error de la API.
+func TestCountry(t *testing.T) {
+	repo := &referenceRepository{}
+	s := newBenchmarkService(repo, false)
+	ctx := context.Background()
+
+	country, err := s.Country(ctx, "AR")
+	if err != nil || country.ID != "AR" || country.CurrencyId != "ARS" {
+		t.Fatalf("se obtuvo %+v, %v; se esperaba Argentina", country, err)
+	}
+	if _, err := s.Country(ctx, "XX"); !errors.Is(err, ErrCountryNotFound) {
+		t.Errorf("país desconocido: se obtuvo %v; se esperaba ErrCountryNotFound", err)
+	}
+
+	repo.failing.Store(true)
+	if _, err := s.Country(ctx, "AR"); !errors.Is(err, api.ErrUnavailable) || errors.Is(err, ErrCountryNotFound) {
+		t.Errorf("API caida: se obtuvo %v; se esperaba api.ErrUnavailable", err)
+	}
+}
+
+// TestCurrencyRate verifica que solo se cotizan monedas de MELI.
+func TestCurrencyRate(t *testing.T) {
+	repo := &referenceRepository{}
+	s := newBenchmarkService(repo, false)
+	ctx := context.Background()
+
+	rate, err := s.CurrencyRate(ctx, "ARS", "USD")
+	if err != nil || rate.Rate != 0.001 {
+		t.Fatalf("se obtuvo %+v, %v; se esperaba la cotizacion 0.001", rate, err)
+	}
+	for _, pair := range [][2]string{{"XXX", "USD"}, {"ARS", "XXX"}} {
+		calls := repo.calls.Load()
+		if _, err := s.CurrencyRate(ctx, pair[0], pair[1]); !errors.Is(err, ErrUnknownCurrency) {
+			t.Errorf("%s a %s: se obtuvo %v; se esperaba ErrUnknownCurrency", pair[0], pair[1], err)
+		}
+		// solo se consulta la lista de monedas, no la cotizacion
+		if got := repo.calls.Load() - calls; got != 1 {
+			t.Errorf("%s a %s: %d consultas; se esperaba 1", pair[0], pair[1], got)
+		}
+	}
+}
+
+// TestLocations verifica el detalle de estados y ciudades y que un ID desconocido para MELI se
+// informe con ErrLocationNotFound.
+func TestLocations(t *testing.T) {
+	repo := &referenceRepository{}
+	s := newBenchmarkService(repo, false)
+	ctx := context.Background()
+
+	if state, err := s.State(ctx, "AR-C"); err != nil || len(state.Cities) != 1 {
+		t.Errorf("se obtuvo %+v, %v; se esperaba el estado con sus ciudades", state, err)
+	}
+	if city, err := s.City(ctx, "TUxBQ1BBTDI1MTVh"); err != nil || city.State.ID != "AR-C" {
+		t.Errorf("se obtuvo %+v, %v; se esperaba la ciudad con su estado", city, err)
+	}
+	if _, err := s.State(ctx, "AR-XX"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("estado desconocido: se obtuvo %v; se esperaba ErrLocationNotFound", err)
+	}
+	if _, err := s.City(ctx, "XX"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("ciudad desconocida: se obtuvo %v; se esperaba ErrLocationNotFound", err)
+	}
+
+	repo.failing.Store(true)
+	if _, err := s.State(ctx, "AR-C"); !errors.Is(err, api.ErrUnavailable) || errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("API caida: se obtuvo %v; se esperaba api.ErrUnavailable", err)
+	}
+}
@@ -0,0 +1,173 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Valores por defecto del refresco de los datos de referencia.
+const (
+	DefaultRefreshInterval = 30 * time.Second
+	DefaultRefreshAhead    = 2 * time.Minute
+)
+
+// refresher precarga los datos de referencia de MELI que necesita la resolucion de IPs (lista de
+// países, detalle de cada país, su cotización a USD y la lista de monedas) y los refresca antes de
+// que venzan, para que las consultas no esperen a MELI. Si un refresco falla el ultimo valor obtenido
+// se sigue sirviendo, marcado como vencido, durante el margen Stale de la caché.
+type refresher struct {
+	r        *cachedRepository
+	interval time.Duration // cada cuanto se revisan los vencimientos
+	ahead    time.Duration // margen antes del vencimiento en el que se refresca un dato
+
+	mu       sync.Mutex
+	datasets map[string]*models.RefreshStatus
+}
+
+// newRefresher crea un refresher sobre la caché de r.
+func newRefresher(r *cachedRepository, interval, ahead time.Duration) *refresher {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	if ahead <= 0 {
+		ahead = DefaultRefreshAhead
+	}
+	return &refresher{
+		r:        r,
+		interval: interval,
+		ahead:    ahead,
+		datasets: make(map[string]*models.RefreshStatus),
+	}
+}
+
+// run precarga los datos y luego los revisa cada interval.
+func (f *refresher) run() {
+	f.refreshAll(context.Background())
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		f.refreshAll(context.Background())
+	}
+}
+
+// refreshAll refresca los datos que no estan en la caché o vencen dentro de ahead.
+func (f *refresher) refreshAll(ctx context.Context) {
+	r := f.r
+	f.refresh(ctx, currenciesCacheKey, fixed(r.times.Currencies), func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCurrencies(ctx)
+	})
+
+	countries, ok := f.refresh(ctx, countriesCacheKey, fixed(r.times.Countries), func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCountries(ctx)
+	}).([]models.Country)
+	if !ok {
+		return
+	}
+
+	for _, c := range countries {
+		countryID := c.ID
+		country, ok := f.refresh(ctx, countryCacheKey+countryID, fixed(r.times.Country), func(ctx context.Context) (interface{}, error) {
+			return r.Repository.FetchCountryById(ctx, countryID)
+		}).(*models.CountryInfo)
+		if !ok || country.CurrencyId == "" {
+			continue
+		}
+
+		currencyID := country.CurrencyId
+		f.refresh(ctx, currencyRateCacheKey+currencyID+":"+BaseCurrency, r.rateTTL, func(ctx context.Context) (interface{}, error) {
+			return r.Repository.FetchCurrencyConversion(ctx, currencyID, BaseCurrency)
+		})
+	}
+}
+
+// refresh obtiene key con fn si no esta en la caché o vence dentro de ahead, y la guarda por el
+// tiempo que retorna ttl. Si fn falla el valor anterior queda en la caché sin cambios, para que se
+// sirva vencido hasta el proximo intento. fn tiene el plazo Refresh de la caché. Si la caché ya
+// esta revalidando key (ver cache.Fetch) no se consulta fn. Retorna el ultimo valor obtenido, o nil
+// si nunca se pudo obtener.
+func (f *refresher) refresh(ctx context.Context, key string, ttl func(val interface{}) time.Duration, fn func(ctx context.Context) (interface{}, error)) interface{} {
+	current, cached := f.r.cache.Peek(key)
+	if cached && time.Until(current.ExpiresAt) > f.ahead {
+		return current.Data
+	}
+
+	var val interface{}
+	var valid time.Duration
+	refreshed, err := f.r.cache.Refresh(ctx, key, func(ctx context.Context) (interface{}, time.Duration, error) {
+		// Cada consulta tiene su propio plazo, para que un MELI que no responde no detenga el refresco
+		ctx, cancel := context.WithTimeout(ctx, f.r.times.Refresh)
+		defer cancel()
+
+		var err error
+		if val, err = fn(ctx); err != nil {
+			return nil, 0, err
+		}
+		valid = ttl(val)
+		return val, valid, nil
+	})
+	now := time.Now().UTC()
+	switch {
+	case !refreshed:
+		// La revalidacion en curso registra su propio resultado en la caché
+		if !cached {
+			return nil
+		}
+		return current.Data
+	case err != nil:
+		f.record(key, now, time.Time{}, err)
+		if !cached {
+			return nil
+		}
+		return current.Data
+	}
+
+	f.record(key, now, now.Add(valid), nil)
+	return val
+}
+
+// record registra el resultado de un refresco.
+func (f *refresher) record(key string, at, expiresAt time.Time, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status, ok := f.datasets[key]
+	if !ok {
+		status = &models.RefreshStatus{Name: key}
+		f.datasets[key] = status
+	}
+	status.LastAttempt = at
+	if err != nil {
+		status.Failures++
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+		log.Printf("[WARN] No se pudo refrescar %s (%d fallas consecutivas): %v", key, status.ConsecutiveFailures, err)
+		return
+	}
+	status.Refreshes++
+	status.ConsecutiveFailures = 0
+	status.LastError = ""
+	status.LastSuccess = &at
+	status.ExpiresAt = &expiresAt
+}
+
+// statuses retorna el estado de refresco de cada dato ordenado por nombre. Un refresher nil (refresco
+// desactivado) no tiene datos.
+func (f *refresher) statuses() []models.RefreshStatus {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	statuses := make([]models.RefreshStatus, 0, len(f.datasets))
+	for _, status := range f.datasets {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,183 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// refreshedKeys son las claves que precarga el refresher con countingRepository.
+var refreshedKeys = []string{currenciesCacheKey, countriesCacheKey, countryCacheKey + "AR", currencyRateCacheKey + "ARS:" + BaseCurrency}
+
+// newTestRefresher crea un refresher, con un minuto de anticipacion, sobre la caché de repo.
+func newTestRefresher(repo *countingRepository) (*refresher, *cachedRepository) {
+	r := newCachedRepository(repo, CacheTimes{})
+	return newRefresher(r, time.Minute, time.Minute), r
+}
+
+// refreshStatus retorna el estado de refresco de key.
+func refreshStatus(t *testing.T, f *refresher, key string) models.RefreshStatus {
+	t.Helper()
+	for _, status := range f.statuses() {
+		if status.Name == key {
+			return status
+		}
+	}
+	t.Fatalf("no hay estado de refresco para %s", key)
+	return models.RefreshStatus{}
+}
+
+// TestRefresherPrefetch verifica que la precarga guarda todos los datos de referencia, de modo
+// que las consultas siguientes no esperan a MELI.
+func TestRefresherPrefetch(t *testing.T) {
+	repo := &countingRepository{}
+	f, r := newTestRefresher(repo)
+	ctx := context.Background()
+
+	f.refreshAll(ctx)
+	if calls := repo.calls.Load(); calls != int64(len(refreshedKeys)) {
+		t.Fatalf("se esperaban %d consultas, se hicieron %d", len(refreshedKeys), calls)
+	}
+	for _, key := range refreshedKeys {
+		if _, ok := r.cache.Peek(key); !ok {
+			t.Errorf("%s no se precargo", key)
+		}
+		if status := refreshStatus(t, f, key); status.Refreshes != 1 || status.LastSuccess == nil || status.ExpiresAt == nil {
+			t.Errorf("estado de %s inesperado: %+v", key, status)
+		}
+	}
+
+	if _, err := r.FetchCurrencyConversion(ctx, "ARS", BaseCurrency); err != nil {
+		t.Fatal(err)
+	}
+	if calls := repo.calls.Load(); calls != int64(len(refreshedKeys)) {
+		t.Fatalf("la consulta de un dato precargado no deberia consultar a MELI (%d consultas)", calls)
+	}
+}
+
+// TestRefresherAhead verifica que solo se refrescan los datos que vencen dentro de ahead.
+func TestRefresherAhead(t *testing.T) {
+	repo := &countingRepository{}
+	f, r := newTestRefresher(repo)
+	ctx := context.Background()
+	f.refreshAll(ctx)
+	before := repo.calls.Load()
+
+	f.refreshAll(ctx)
+	if calls := repo.calls.Load() - before; calls != 0 {
+		t.Fatalf("con todos los datos vigentes no se esperaban consultas, se hicieron %d", calls)
+	}
+
+	// La cotización vence antes de ahead: es la unica que se refresca
+	rateKey := currencyRateCacheKey + "ARS:" + BaseCurrency
+	current, _ := r.cache.Peek(rateKey)
+	r.cache.SetWithTTL(rateKey, current.Data, 30*time.Second)
+	f.refreshAll(ctx)
+	if calls := repo.calls.Load() - before; calls != 1 {
+		t.Fatalf("se esperaba 1 consulta, se hicieron %d", calls)
+	}
+	if status := refreshStatus(t, f, rateKey); status.Refreshes != 2 {
+		t.Fatalf("se esperaban 2 refrescos de la cotización, se hicieron %d", status.Refreshes)
+	}
+	if item, _ := r.cache.Peek(rateKey); time.Until(item.ExpiresAt) <= f.ahead {
+		t.Fatalf("la cotización refrescada vence en %v", time.Until(item.ExpiresAt))
+	}
+}
+
+// TestRefresherKeepsLastValue verifica que si un refresco falla se conserva el ultimo valor, se
+// cuentan las fallas y el servicio se informa degradado hasta el proximo refresco exitoso.
+func TestRefresherKeepsLastValue(t *testing.T) {
+	repo := &countingRepository{}
+	f, r := newTestRefresher(repo)
+	s := &service{breakers: newBreakerRepository(repo, 0, 0, nil), refresher: f}
+	ctx := context.Background()
+	f.refreshAll(ctx)
+
+	countryKey := countryCacheKey + "AR"
+	last, _ := r.cache.Peek(countryKey)
+	r.cache.SetWithTTL(countryKey, last.Data, -time.Second)
+	repo.failing.Store(true)
+	for i := 1; i <= 2; i++ {
+		f.refreshAll(ctx)
+		if item, ok := r.cache.Peek(countryKey); !ok || item.Data != last.Data {
+			t.Fatalf("se esperaba conservar el ultimo valor, se obtuvo %+v", item)
+		}
+		status := refreshStatus(t, f, countryKey)
+		if status.Failures != int64(i) || status.ConsecutiveFailures != i || status.LastError == "" || status.Refreshes != 1 {
+			t.Fatalf("estado tras %d fallas inesperado: %+v", i, status)
+		}
+	}
+	if health := s.Health(); health.Status != models.HealthDegraded {
+		t.Fatalf("se esperaba el servicio degradado, esta %s", health.Status)
+	}
+
+	repo.failing.Store(false)
+	f.refreshAll(ctx)
+	status := refreshStatus(t, f, countryKey)
+	if status.Failures != 2 || status.ConsecutiveFailures != 0 || status.LastError != "" || status.Refreshes != 2 {
+		t.Fatalf("estado tras recuperarse inesperado: %+v", status)
+	}
+	if health := s.Health(); health.Status != models.HealthOK {
+		t.Fatalf("se esperaba el servicio ok, esta %s", health.Status)
+	}
+}
+
+// TestRefresherSkipsRevalidation verifica que el refresher no consulta una clave que la caché ya
+// esta revalidando por una consulta.
+func TestRefresherSkipsRevalidation(t *testing.T) {
+	repo := &countingRepository{}
+	f, r := newTestRefresher(repo)
+	ctx := context.Background()
+	f.refreshAll(ctx)
+
+	countryKey := countryCacheKey + "AR"
+	last, _ := r.cache.Peek(countryKey)
+	r.cache.SetWithTTL(countryKey, last.Data, -time.Second)
+
+	release := make(chan struct{})
+	revalidated := make(chan struct{})
+	_, stale, err := r.cache.Fetch(ctx, countryKey, func(ctx context.Context) (interface{}, time.Duration, error) {
+		defer close(revalidated)
+		<-release
+		return last.Data, time.Hour, nil
+	})
+	if err != nil || !stale {
+		t.Fatalf("se esperaba el valor vencido mientras se revalida: %v, %v", stale, err)
+	}
+
+	called := false
+	got := f.refresh(ctx, countryKey, fixed(time.Hour), func(ctx context.Context) (interface{}, error) {
+		called = true
+		return last.Data, nil
+	})
+	if called || got != last.Data {
+		t.Fatalf("no se esperaba refrescar una clave en revalidacion (consultada: %v)", called)
+	}
+	if status := refreshStatus(t, f, countryKey); status.Refreshes != 1 || status.Failures != 0 {
+		t.Fatalf("el refresco omitido no deberia registrarse: %+v", status)
+	}
+
+	close(release)
+	<-revalidated
+}
+
+// TestRefresherTimeout verifica que cada consulta del refresco termina al vencer el plazo Refresh
+// aunque MELI no responda, y que la falla se registra en el estado del dato.
+func TestRefresherTimeout(t *testing.T) {
+	repo := &countingRepository{latency: time.Hour}
+	r := newCachedRepository(repo, CacheTimes{Refresh: 20 * time.Millisecond})
+	f := newRefresher(r, time.Minute, time.Minute)
+
+	start := time.Now()
+	f.refreshAll(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("el refresco tardo %v; se esperaba que cada consulta terminara al vencer su plazo", elapsed)
+	}
+	for _, key := range []string{currenciesCacheKey, countriesCacheKey} {
+		if status := refreshStatus(t, f, key); status.Failures != 1 || !strings.Contains(status.LastError, context.DeadlineExceeded.Error()) {
+			t.Errorf("estado de %s inesperado: %+v", key, status)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package ipinfo
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// matchRegion busca el estado de MELI cuyo nombre corresponde a la region informada por IP2Location.
+// Se prioriza la coincidencia exacta (sin acentos ni mayusculas) y luego la parcial
+// ("Buenos Aires" con "Provincia de Buenos Aires").
+func matchRegion(info *models.IPInfo, states []models.State) *models.RegionMatch {
+	match := &models.RegionMatch{Region: info.Region, City: info.City}
+	region := normalizeName(info.Region)
+
+	var partial *models.State
+	for i, state := range states {
+		name := normalizeName(state.Name)
+		if name == region {
+			match.StateID, match.StateName = state.ID, state.Name
+			return match
+		}
+		if partial == nil && name != "" && (strings.Contains(name, region) || strings.Contains(region, name)) {
+			partial = &states[i]
+		}
+	}
+
+	if partial != nil {
+		match.StateID, match.StateName = partial.ID, partial.Name
+	}
+	return match
+}
+
+// normalizeName pasa a minusculas y quita acentos para comparar nombres de lugares.
+func normalizeName(name string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	normalized, _, err := transform.String(stripAccents, name)
+	if err != nil {
+		normalized = name
+	}
+	return strings.ToLower(strings.TrimSpace(normalized))
+}
@@ -0,0 +1,84 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"testing"
+)
+
+// TestMatchRegion verifica la asociacion de la region de IP2Location con un estado de MELI.
+func TestMatchRegion(t *testing.T) {
+	states := []models.State{
+		{ID: "AR-B", Name: "Buenos Aires"},
+		{ID: "AR-C", Name: "Capital Federal"},
+		{ID: "AR-X", Name: "Córdoba"},
+		{ID: "AR-E", Name: "Entre Ríos"},
+		{ID: "AR-Q", Name: ""},
+	}
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{"Cordoba", "AR-X"},
+		{"  ENTRE RIOS ", "AR-E"},
+		// la coincidencia exacta tiene prioridad sobre la parcial
+		{"Buenos Aires", "AR-B"},
+		{"Provincia de Buenos Aires", "AR-B"},
+		{"Capital", "AR-C"},
+		{"Tierra del Fuego", ""},
+	}
+	for _, tt := range tests {
+		info := &models.IPInfo{Region: tt.region, City: "Ciudad"}
+		match := matchRegion(info, states)
+		if match.StateID != tt.want || match.Region != tt.region || match.City != "Ciudad" {
+			t.Errorf("matchRegion(%q) = %+v; se esperaba el estado %q", tt.region, match, tt.want)
+		}
+		if tt.want == "" && match.StateName != "" {
+			t.Errorf("matchRegion(%q) = %+v; se esperaba sin estado", tt.region, match)
+		}
+	}
+}
+
+// regionRepository responde una IP de la region indicada y un país con un unico estado, Córdoba.
+type regionRepository struct {
+	countingRepository
+	region string
+}
+
+func (r *regionRepository) GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error) {
+	return &models.IPInfo{IP: ip, CountryCode: "AR", CountryName: "Argentina", Region: r.region}, nil
+}
+
+func (r *regionRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	return &models.CountryInfo{Country: models.Country{ID: countryID}, States: []models.State{{ID: "AR-X", Name: "Córdoba"}}}, nil
+}
+
+// TestLookupRegion verifica que la consulta de una IP informa el estado de su region, sin
+// estado si no hay coincidencia, y que no informa region si IP2Location no la provee.
+func TestLookupRegion(t *testing.T) {
+	tests := []struct {
+		region  string
+		want    string
+		noMatch bool
+	}{
+		{"Cordoba", "AR-X", false},
+		{"Mendoza", "", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		s := newBenchmarkService(&regionRepository{region: tt.region}, false)
+		info, err := s.GetCountryDataByIP(context.Background(), "181.0.0.1", LookupOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.noMatch {
+			if info.Region != nil {
+				t.Errorf("sin region se obtuvo %+v; se esperaba nil", info.Region)
+			}
+			continue
+		}
+		if info.Region == nil || info.Region.Region != tt.region || info.Region.StateID != tt.want {
+			t.Errorf("region %q: se obtuvo %+v; se esperaba el estado %q", tt.region, info.Region, tt.want)
+		}
+	}
+}
@@ -1,17 +1,20 @@
 package ipinfo
 
 import (
+	"context"
 	"github.com/AleHts29/meli-challenge/internal/models"
 	"github.com/AleHts29/meli-challenge/pkg/api"
 	"github.com/AleHts29/meli-challenge/pkg/store"
 )
 
 type Repository interface {
-	FetchCountries() ([]models.Country, error)
-	FetchCountryById(countryID string) (*models.CountryInfo, error)
-	FetchCurrenciesConversionToUSD(currencyId string) (*models.CurrencyExchange, error)
-	FetchCurrencies() ([]models.Currency, error)
-	GetCountryByIP(ip string) (*models.IPInfo, error)
+	FetchCountries(ctx context.Context) ([]models.Country, error)
+	FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error)
+	FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error)
+	FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error)
+	FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error)
+	FetchCurrencies(ctx context.Context) ([]models.Currency, error)
+	GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error)
 }
 
 type repository struct {
@@ -30,8 +33,8 @@ func NewRepository(apiCountries api.Countries, apiCurrencies api.Currencies, ipS
 }
 
 // FetchCountries consulta la API de Mercado Libre para obtener información sobre países.
-func (r *repository) FetchCountries() ([]models.Country, error) {
-	countries, err := r.apiCountries.FetchCountries()
+func (r *repository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	countries, err := r.apiCountries.FetchCountries(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -39,33 +42,52 @@ func (r *repository) FetchCountries() ([]models.Country, error) {
 }
 
 // FetchCountryById consulta la API de Mercado Libre para obtener información sobre un país específico.
-func (r *repository) FetchCountryById(countryID string) (*models.CountryInfo, error) {
-	country, err := r.apiCountries.FetchCountryById(countryID)
+func (r *repository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	country, err := r.apiCountries.FetchCountryById(ctx, countryID)
 	if err != nil {
 		return nil, err
 	}
 	return country, nil
 }
 
+// FetchStateById consulta la API de Mercado Libre para obtener un estado con sus ciudades.
+func (r *repository) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	state, err := r.apiCountries.FetchStateById(ctx, stateID)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// FetchCityById consulta la API de Mercado Libre para obtener una ciudad con sus barrios.
+func (r *repository) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	city, err := r.apiCountries.FetchCityById(ctx, cityID)
+	if err != nil {
+		return nil, err
+	}
+	return city, nil
+}
+
 // FetchCurrencies consulta la API de Mercado Libre para obtener información sobre las monedas.
-func (r *repository) FetchCurrencies() ([]models.Currency, error) {
-	currencies, err := r.apiCurrencies.FetchCurrencies()
+func (r *repository) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
+	currencies, err := r.apiCurrencies.FetchCurrencies(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return currencies, nil
 }
 
-func (r *repository) FetchCurrenciesConversionToUSD(currencyId string) (*models.CurrencyExchange, error) {
-	currency, err := r.apiCurrencies.FetchCurrenciesConversionToUSD(currencyId)
+// FetchCurrencyConversion consulta la API de Mercado Libre para obtener la cotización entre dos monedas.
+func (r *repository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	currency, err := r.apiCurrencies.FetchCurrencyConversion(ctx, from, to)
 	if err != nil {
 		return nil, err
 	}
 	return currency, nil
 }
 
-func (r *repository) GetCountryByIP(ip string) (*models.IPInfo, error) {
-	byIP, err := r.ipStore.GetCountryByIP(ip)
+func (r *repository) GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error) {
+	byIP, err := r.ipStore.GetCountryByIP(ctx, ip)
 	if err != nil {
 		return nil, err
 	}
@@ -1,100 +1,403 @@
 package ipinfo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/config"
 	"github.com/AleHts29/meli-challenge/internal/models"
 	"github.com/AleHts29/meli-challenge/pkg/cache"
 	"log"
+	"math"
+	"net"
 	"os"
+	"sort"
 	"sync"
 	"time"
 )
 
 const (
-	BufferSizeEvents  = 256
-	BufferSizeClients = 10
-	CacheTime         = 5 * time.Minute
+	BufferSizeClients  = 10
+	CacheTime          = 5 * time.Minute
+	PartialCacheTime   = 30 * time.Second
+	BlockSweepInterval = 30 * time.Second
+	BaseCurrency       = "USD"
 )
 
 type Service interface {
-	GetCountryDataByIP(ip string) (*models.CountryInfo, error)
-	BlockIP(ip string) error
+	GetCountryDataByIP(ctx context.Context, ip string, opts LookupOptions) (*models.CountryInfo, error)
+	LookupBatch(ctx context.Context, ips []string, concurrency int, opts LookupOptions) <-chan models.LookupResult
+	ConvertAmount(ctx context.Context, from string, to []string, amount float64) ([]models.CurrencyConversion, error)
+	Stats() models.UsageStats
+	Health() models.Health
+	Countries(ctx context.Context) ([]models.Country, error)
+	Country(ctx context.Context, countryID string) (*models.CountryInfo, error)
+	State(ctx context.Context, stateID string) (*models.StateInfo, error)
+	City(ctx context.Context, cityID string) (*models.CityInfo, error)
+	Currencies(ctx context.Context) ([]models.Currency, error)
+	CurrencyRate(ctx context.Context, from, to string) (*models.CurrencyExchange, error)
+	BlockIP(ip string, ttl time.Duration) error
+	UnblockIP(ip string) (bool, error)
 	IsBlocked(ip string) bool
-	SubscribeEvents() chan models.BlockEvent
-	UnsubscribeEvents(clientChan chan models.BlockEvent)
+	BlockedIPs() []BlockedIP
+	ReportLookupDenied(ip string, payload models.LookupDeniedPayload)
+	SubscribeEvents() chan models.Event
+	UnsubscribeEvents(clientChan chan models.Event)
 }
 
 type service struct {
 	r         Repository
+	breakers  *breakerRepository
+	lookups   *flightGroup // consultas de IPs en curso, para coalescer las de la misma IP
+	refresher *refresher   // refresco de los datos de referencia; nil si esta desactivado
 	blockList *BlockList
-	cache     *cache.Cache
-	events    chan models.BlockEvent
+	cache     *cache.Cache // resultados por IP; los datos de referencia tienen su propia caché (cachedRepository)
+	stats     *usageStats
 	mu        sync.Mutex
-	clients   map[chan models.BlockEvent]struct{}
-	filePath  string // ruta del archivo para guardar estados de la aplicacion
+	clients   map[chan models.Event]struct{}
+	reloaded  *models.Event // evento STATE_RELOADED, que se reenvia a cada suscriptor nuevo
+	events    models.EventsStatus
+	fileMu    sync.Mutex // serializa la lectura y escritura de filePath
+	filePath  string     // ruta del archivo para guardar estados de la aplicacion
 }
 
 // NewService crea una nueva instancia del servicio.
-func NewService(r Repository, filePath string) Service {
+func NewService(r Repository, cfg *config.Config) Service {
 	service := &service{
-		r:         r,
+		lookups:   newFlightGroup(),
 		blockList: NewBlockList(),
-		cache:     cache.NewCache(CacheTime),
-		events:    make(chan models.BlockEvent, BufferSizeEvents),
-		clients:   make(map[chan models.BlockEvent]struct{}),
-		filePath:  filePath,
+		cache:     cache.NewCache(ipCacheTime(cfg.CacheTTL)),
+		stats:     newUsageStats(models.Location{Latitude: cfg.ReferenceLatitude, Longitude: cfg.ReferenceLongitude}),
+		clients:   make(map[chan models.Event]struct{}),
+		filePath:  cfg.BlockedIPsFilePath,
 	}
-	go service.loadBlockedIPs()
+	// Todas las consultas a MELI pasan por un circuit breaker por operacion. Las consultas
+	// concurrentes con los mismos parametros se coalescen antes del breaker, por lo que una
+	// falla compartida cuenta una sola vez, y los resultados se guardan en la caché de datos
+	// de referencia.
+	service.breakers = newBreakerRepository(r, cfg.BreakerFailureThreshold, cfg.BreakerOpenTimeout, service.reportBreakerOpen)
+	cached := newCachedRepository(newFlightRepository(service.breakers), CacheTimes{
+		Countries:  cfg.CacheTTLCountries,
+		Country:    cfg.CacheTTLCountry,
+		Location:   cfg.CacheTTLLocation,
+		Currencies: cfg.CacheTTLCurrencies,
+		Rate:       cfg.CacheTTLRate,
+		Stale:      cfg.CacheStaleTTL,
+		Refresh:    cfg.RequestTimeout,
+	})
+	service.r = cached
+	if cfg.RefreshInterval > 0 {
+		service.refresher = newRefresher(cached, cfg.RefreshInterval, cfg.RefreshAhead)
+		go service.refresher.run()
+	}
+	go service.reloadState()
+	go service.sweepExpiredBlocks(time.NewTicker(BlockSweepInterval).C)
 	return service
 }
 
+// ipCacheTime retorna el tiempo de vida de los resultados por IP, o CacheTime si ttl no es positivo.
+func ipCacheTime(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return CacheTime
+	}
+	return ttl
+}
+
 ////////////////////////////////
 // *** DATA COUNTRIES ***
 
+// LookupOptions modifica el comportamiento de la resolucion de una IP.
+type LookupOptions struct {
+	// Strict hace fallar la consulta si alguna API de MELI falla, en lugar de
+	// responder solo con la geolocalizacion y la informacion que se pudo obtener.
+	Strict bool
+}
+
 // GetCountryDataByIP obtiene información de un país a partir de una IP.
-func (s *service) GetCountryDataByIP(ip string) (*models.CountryInfo, error) {
-	// Verificar si la información ya está en la caché
+func (s *service) GetCountryDataByIP(ctx context.Context, ip string, opts LookupOptions) (*models.CountryInfo, error) {
+	countryInfo, err := s.lookup(ctx, s.r, ip, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.complete(ctx, countryInfo, opts), nil
+}
+
+// complete registra la consulta en las estadisticas y agrega los valores que dependen del momento de la consulta.
+func (s *service) complete(ctx context.Context, countryInfo *models.CountryInfo, opts LookupOptions) *models.CountryInfo {
+	s.stats.record(countryInfo)
+	return s.localize(ctx, countryInfo, opts)
+}
+
+// Stats retorna las estadisticas de consultas por país y su distancia al punto de referencia.
+func (s *service) Stats() models.UsageStats {
+	return s.stats.snapshot()
+}
+
+// Health retorna el estado de los circuit breakers de las APIs de MELI y del refresco de los
+// datos de referencia. El servicio esta degradado si algun breaker no esta cerrado o si el
+// ultimo refresco de algun dato fallo.
+func (s *service) Health() models.Health {
+	health := models.Health{
+		Status:   models.HealthOK,
+		Breakers: s.breakers.statuses(),
+		Refresh:  s.refresher.statuses(),
+	}
+	for _, breaker := range health.Breakers {
+		if breaker.State != models.BreakerClosed {
+			health.Status = models.HealthDegraded
+		}
+	}
+	for _, refresh := range health.Refresh {
+		if refresh.ConsecutiveFailures > 0 {
+			health.Status = models.HealthDegraded
+		}
+	}
+
+	s.mu.Lock()
+	health.Events = s.events
+	health.Events.Subscribers = len(s.clients)
+	s.mu.Unlock()
+	return health
+}
+
+// LookupBatch resuelve un lote de IPs con a lo sumo concurrency consultas en paralelo.
+// Las consultas a las APIs externas se comparten entre todas las IPs del lote.
+// El canal devuelto se cierra cuando se resolvieron todas las IPs. Si ctx se cancela o vence,
+// las IPs que todavia no se empezaron a resolver se informan como fallidas con el error de ctx.
+func (s *service) LookupBatch(ctx context.Context, ips []string, concurrency int, opts LookupOptions) <-chan models.LookupResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(chan models.LookupResult, len(ips))
+	shared := newSharedRepository(s.r)
+	sem := make(chan struct{}, concurrency)
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for i, ip := range ips {
+			if ctx.Err() == nil {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				for _, pending := range ips[i:] {
+					results <- models.LookupResult{IP: pending, Status: models.LookupUpstreamFailed, Error: err.Error(), Err: err}
+				}
+				return
+			}
+
+			wg.Add(1)
+			go func(ip string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				results <- s.lookupResult(ctx, shared, ip, opts)
+			}(ip)
+		}
+	}()
+
+	return results
+}
+
+// lookupResult resuelve una IP y clasifica el resultado.
+func (s *service) lookupResult(ctx context.Context, r Repository, ip string, opts LookupOptions) models.LookupResult {
+	countryInfo, err := s.lookup(ctx, r, ip, opts)
+	if err == nil {
+		countryInfo = s.complete(ctx, countryInfo, opts)
+	}
+	var specialPurpose *SpecialPurposeError
+	switch {
+	case err == nil && countryInfo.Partial:
+		return models.LookupResult{IP: ip, Status: models.LookupPartial, Data: countryInfo}
+	case err == nil:
+		return models.LookupResult{IP: ip, Status: models.LookupOK, Data: countryInfo}
+	case errors.As(err, &specialPurpose):
+		return models.LookupResult{IP: ip, Status: models.LookupSpecialPurpose, SpecialPurpose: &specialPurpose.Address, Error: err.Error(), Err: err}
+	case errors.Is(err, ErrCountryNotOperated):
+		return models.LookupResult{IP: ip, Status: models.LookupNonMeli, Error: err.Error(), Err: err}
+	default:
+		return models.LookupResult{IP: ip, Status: models.LookupUpstreamFailed, Error: err.Error(), Err: err}
+	}
+}
+
+// lookup resuelve una IP con resolve. Las consultas concurrentes de una IP que no esta en la
+// caché comparten una unica resolucion.
+func (s *service) lookup(ctx context.Context, r Repository, ip string, opts LookupOptions) (*models.CountryInfo, error) {
+	if cached, ok := s.cached(ip, opts); ok {
+		return cached, nil
+	}
+
+	// Una consulta estricta no puede reutilizar una resolucion que acepta resultados parciales
+	key := ip
+	if opts.Strict {
+		key = "strict:" + ip
+	}
+	val, err := s.lookups.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return s.resolve(ctx, r, ip, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CountryInfo), nil
+}
+
+// cached retorna la información de una IP si está en la caché. En modo estricto no se aceptan
+// resultados parciales.
+func (s *service) cached(ip string, opts LookupOptions) (*models.CountryInfo, bool) {
 	if data, ok := s.cache.Get(ip); ok {
-		return data.(*models.CountryInfo), nil
+		if cached := data.(*models.CountryInfo); !cached.Partial || !opts.Strict {
+			return cached, true
+		}
+	}
+	return nil, false
+}
+
+// resolve obtiene la información del país de una IP usando el repositorio indicado.
+// Si falla alguna API de MELI y no se pidio modo estricto, se responde con la
+// geolocalizacion y lo que se haya podido obtener, marcando el resultado como parcial.
+func (s *service) resolve(ctx context.Context, r Repository, ip string, opts LookupOptions) (*models.CountryInfo, error) {
+	// Las IPs de proposito especial (privadas, loopback, documentacion, etc.) no se geolocalizan
+	if address, ok := ClassifySpecialPurpose(net.ParseIP(ip)); ok {
+		return nil, &SpecialPurposeError{Address: *address}
 	}
 
+	// Verificar si la información ya está en la caché (otra consulta pudo resolverla mientras tanto)
+	if cached, ok := s.cached(ip, opts); ok {
+		return cached, nil
+	}
+
+	// Los datos de referencia vencidos que sirva la caché se informan en la respuesta
+	ctx, stale := withStaleTracking(ctx)
+
 	// Consultar la información desde el repositorio (APIs externas)
-	info, err := s.r.GetCountryByIP(ip)
+	info, err := r.GetCountryByIP(ctx, ip)
 	if err != nil {
 		return nil, fmt.Errorf("error al obtener información del país para la IP: %w", err)
 	}
 
+	// La geolocalizacion es la base de la respuesta parcial
+	countryInfo := &models.CountryInfo{Country: models.Country{ID: info.CountryCode, Name: info.CountryName}, IPInfo: info}
+
 	// Obtener la lista de países en los que opera MELI
-	countries, err := s.r.FetchCountries()
+	countries, err := r.FetchCountries(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error al obtener la lista de países: %w", err)
+		s.reportUpstreamFailure("countries", err)
+		if opts.Strict {
+			return nil, fmt.Errorf("error al obtener la lista de países: %w", err)
+		}
+		countryInfo.AddWarning("countries", err)
+	} else if !s.isValidCountry(info.CountryCode, countries) {
+		// Validar si el CountryCode está en la lista de países
+		return nil, fmt.Errorf("el país con código '%s' no es válido: %w", info.CountryCode, ErrCountryNotOperated)
 	}
 
-	// Validar si el CountryCode está en la lista de países
-	if !s.isValidCountry(info.CountryCode, countries) {
-		return nil, fmt.Errorf("el país con código '%s' no es válido o no opera MercadoLibre", info.CountryCode)
+	country, err := r.FetchCountryById(ctx, info.CountryCode)
+	if err != nil {
+		s.reportUpstreamFailure("country", err)
+		if opts.Strict {
+			return nil, fmt.Errorf("error al obtener información del país: %w", err)
+		}
+		countryInfo.AddWarning("country", err)
+	} else {
+		// Se copia el país para no modificar una respuesta compartida
+		partial, warnings := countryInfo.Partial, countryInfo.Warnings
+		*countryInfo = *country
+		countryInfo.Partial, countryInfo.Warnings = partial, warnings
+		countryInfo.IPInfo = info
 	}
 
-	countryInfo, err := s.r.FetchCountryById(info.CountryCode)
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener información del país: %w", err)
+	// Estado de MELI que corresponde a la region de la IP, si la base de IP2Location la informa
+	if info.Region != "" {
+		countryInfo.Region = matchRegion(info, countryInfo.States)
 	}
 
-	currencyConversion, err := s.r.FetchCurrenciesConversionToUSD(countryInfo.CurrencyId)
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener la cotización de la moneda: %w", err)
+	// Sin el detalle del pais no se conoce la moneda, por lo que no se puede cotizar
+	if countryInfo.CurrencyId != "" {
+		currencyConversion, err := r.FetchCurrencyConversion(ctx, countryInfo.CurrencyId, BaseCurrency)
+		if err != nil {
+			s.reportUpstreamFailure("currency_conversion", err)
+			if opts.Strict {
+				return nil, fmt.Errorf("error al obtener la cotización de la moneda: %w", err)
+			}
+			countryInfo.AddWarning("currency_conversion", err)
+		} else {
+			// Agregar la cotización al objeto de información del país
+			countryInfo.CurrencyConversionToUSD = currencyConversion
+		}
 	}
 
-	// Agregar la cotización al objeto de información del país
-	countryInfo.CurrencyConversionToUSD = *currencyConversion
+	// Distancia al punto de referencia, si MELI informo la ubicacion del país
+	countryInfo.DistanceKm = s.stats.distanceTo(countryInfo.GeoInformation)
+	countryInfo.MarkStale(stale.list())
 
-	// Guardar el resultado en la caché
-	s.cache.Set(ip, countryInfo)
+	// Guardar el resultado en la caché. Los resultados parciales o con datos vencidos se guardan
+	// por menos tiempo, y no se guardan si la solicitud se cancelo o agoto su plazo, porque la
+	// falla no fue de MELI.
+	if ctx.Err() != nil {
+		return countryInfo, nil
+	}
+	if countryInfo.Partial || countryInfo.Stale {
+		s.cache.SetWithTTL(ip, countryInfo, PartialCacheTime)
+	} else {
+		s.cache.Set(ip, countryInfo)
+	}
 
 	return countryInfo, nil
 }
 
+// ConvertAmount convierte amount de la moneda from a cada una de las monedas to,
+// redondeando con los decimales que MELI define para cada moneda destino.
+func (s *service) ConvertAmount(ctx context.Context, from string, to []string, amount float64) ([]models.CurrencyConversion, error) {
+	currencies, err := s.currencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conversions := make([]models.CurrencyConversion, 0, len(to))
+	for _, target := range to {
+		currency, ok := currencies[target]
+		if !ok {
+			return nil, fmt.Errorf("'%s': %w", target, ErrUnknownCurrency)
+		}
+
+		rate := 1.0
+		if target != from {
+			exchange, err := s.CurrencyRate(ctx, from, target)
+			if err != nil {
+				return nil, err
+			}
+			rate = exchange.Rate
+		}
+
+		conversions = append(conversions, models.CurrencyConversion{
+			From:            from,
+			To:              target,
+			Rate:            rate,
+			Amount:          amount,
+			ConvertedAmount: roundTo(amount*rate, currency.DecimalPlaces),
+			Symbol:          currency.Symbol,
+			DecimalPlaces:   currency.DecimalPlaces,
+		})
+	}
+	return conversions, nil
+}
+
+// roundTo redondea value a la cantidad de decimales indicada.
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
+
 // isValidCountry verifica si el countryCode está en la lista de países.
 func (s *service) isValidCountry(countryCode string, countries []models.Country) bool {
 	for _, country := range countries {
@@ -105,12 +408,33 @@ func (s *service) isValidCountry(countryCode string, countries []models.Country)
 	return false
 }
 
+// reportUpstreamFailure emite un evento UPSTREAM_DEGRADED por la falla de una API externa.
+// Las solicitudes canceladas por el cliente no indican una falla de la API.
+func (s *service) reportUpstreamFailure(source string, err error) {
+	// Con el breaker abierto la falla ya se informo al abrirse
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	s.emit(models.Event{
+		Event:   models.EventUpstreamDegraded,
+		Payload: models.UpstreamDegradedPayload{Source: source, Error: err.Error()},
+	})
+}
+
+// reportBreakerOpen emite un evento UPSTREAM_DEGRADED cuando se abre el circuit breaker de una operacion.
+func (s *service) reportBreakerOpen(source string, err error) {
+	s.emit(models.Event{
+		Event:   models.EventUpstreamDegraded,
+		Payload: models.UpstreamDegradedPayload{Source: source, Error: err.Error(), Breaker: models.BreakerOpen},
+	})
+}
+
 ////////////////////////////////
 // *** BLOCK_IP ***
 
-// BlockIP añade una IP a la lista de bloqueos.
-func (s *service) BlockIP(ip string) error {
-	s.blockList.AddIP(ip)
+// BlockIP añade una IP a la lista de bloqueos. Si ttl es mayor a cero el bloqueo vence pasado ese tiempo.
+func (s *service) BlockIP(ip string, ttl time.Duration) error {
+	entry := s.blockList.AddIP(ip, ttl)
 
 	// Guardar estado de la aplicacion en el archivo
 	err := s.saveBlockedIPs()
@@ -119,96 +443,218 @@ func (s *service) BlockIP(ip string) error {
 	}
 
 	// Envia notificacion de bloqueo a clientes
-	go func() {
-		event := models.BlockEvent{IP: ip, Event: "BLOCKED"}
-		s.notifyClients(event)
-		log.Printf("[INFO] Evento emitido - IP %s bloqueada", ip)
-	}()
+	s.emit(models.Event{
+		IP:      ip,
+		Event:   models.EventBlocked,
+		Payload: models.BlockedPayload{ExpiresAt: entry.ExpiresAt},
+	})
+	log.Printf("[INFO] Evento emitido - IP %s bloqueada", ip)
 
 	return nil
 }
 
+// UnblockIP quita una IP de la lista de bloqueos. Retorna false si la IP no estaba bloqueada.
+func (s *service) UnblockIP(ip string) (bool, error) {
+	entry, ok := s.blockList.RemoveIP(ip)
+	if !ok {
+		return false, nil
+	}
+
+	if err := s.saveBlockedIPs(); err != nil {
+		return true, err
+	}
+
+	s.emit(models.Event{
+		IP:      ip,
+		Event:   models.EventUnblocked,
+		Payload: models.UnblockedPayload{BlockedAt: entry.BlockedAt},
+	})
+	log.Printf("[INFO] Evento emitido - IP %s desbloqueada", ip)
+
+	return true, nil
+}
+
 // IsBlocked retorna el estado de una IP
 func (s *service) IsBlocked(ip string) bool {
 	return s.blockList.IsBlocked(ip)
 }
 
+// BlockedIPs retorna los bloqueos vigentes ordenados por IP.
+func (s *service) BlockedIPs() []BlockedIP {
+	now := time.Now()
+	var blocked []BlockedIP
+	for _, entry := range s.blockList.GetAll() {
+		if !entry.expired(now) {
+			blocked = append(blocked, entry)
+		}
+	}
+	sort.Slice(blocked, func(i, j int) bool { return blocked[i].IP < blocked[j].IP })
+	return blocked
+}
+
+// ReportLookupDenied notifica un intento de consulta sobre una IP bloqueada.
+func (s *service) ReportLookupDenied(ip string, payload models.LookupDeniedPayload) {
+	s.emit(models.Event{
+		IP:      ip,
+		Event:   models.EventLookupDenied,
+		Payload: payload,
+	})
+}
+
+// sweepExpiredBlocks elimina los bloqueos vencidos en cada instante que recibe de ticks.
+func (s *service) sweepExpiredBlocks(ticks <-chan time.Time) {
+	for now := range ticks {
+		expired := s.blockList.RemoveExpired(now)
+		if len(expired) == 0 {
+			continue
+		}
+
+		if err := s.saveBlockedIPs(); err != nil {
+			log.Printf("[ERROR] No se pudo guardar la lista de IPs bloqueadas: %v", err)
+		}
+
+		for _, entry := range expired {
+			s.emit(models.Event{
+				IP:      entry.IP,
+				Event:   models.EventExpired,
+				Payload: models.ExpiredPayload{BlockedAt: entry.BlockedAt, ExpiredAt: *entry.ExpiresAt},
+			})
+			log.Printf("[INFO] Evento emitido - bloqueo de IP %s vencido", entry.IP)
+		}
+	}
+}
+
 ////////////////////////////////
 // *** NOTIFICACIONES ***
 
-// SubscribeEvents permite suscribirse al canal de eventos.
-func (s *service) SubscribeEvents() chan models.BlockEvent {
-	clientChan := make(chan models.BlockEvent, BufferSizeClients)
+// SubscribeEvents permite suscribirse al canal de eventos. Si el estado persistido ya se cargo,
+// el primer evento del canal es su STATE_RELOADED, que se emite antes de que haya suscriptores.
+func (s *service) SubscribeEvents() chan models.Event {
+	clientChan := make(chan models.Event, BufferSizeClients)
 	s.mu.Lock()
 	s.clients[clientChan] = struct{}{}
+	if s.reloaded != nil {
+		clientChan <- *s.reloaded
+	}
 	s.mu.Unlock()
 	return clientChan
 }
 
-// UnsubscribeEvents elimina a un cliente de la lista de suscriptores.
-func (s *service) UnsubscribeEvents(clientChan chan models.BlockEvent) {
+// UnsubscribeEvents elimina a un cliente de la lista de suscriptores. Si ya se habia desconectado
+// por no recibir los eventos a tiempo, su canal ya esta cerrado.
+func (s *service) UnsubscribeEvents(clientChan chan models.Event) {
 	s.mu.Lock()
-	delete(s.clients, clientChan)
-	close(clientChan) // cierra canal del cliente
-	s.mu.Unlock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[clientChan]; ok {
+		delete(s.clients, clientChan)
+		close(clientChan) // cierra canal del cliente
+	}
+}
+
+// emit completa la fecha del evento y lo envía a los suscriptores.
+func (s *service) emit(event models.Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	s.notifyClients(event)
 }
 
 // notifyClients envía un evento a todos los clientes suscritos.
-func (s *service) notifyClients(event models.BlockEvent) {
+func (s *service) notifyClients(event models.Event) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.notifyClientsLocked(event)
+}
+
+// notifyClientsLocked envía un evento a todos los clientes suscritos. Un cliente con el buffer
+// lleno no recibe los eventos a tiempo: para no bloquear al resto ni perder eventos sin avisarle,
+// se desconecta cerrando su canal, despues de los eventos que ya tenia en el buffer (ver
+// EventSubscriptionClosed). Requiere s.mu.
+func (s *service) notifyClientsLocked(event models.Event) {
 	for clientChan := range s.clients {
-		clientChan <- event
+		select {
+		case clientChan <- event:
+		default:
+			delete(s.clients, clientChan)
+			close(clientChan)
+			s.events.Disconnected++
+			s.events.Dropped++
+			log.Printf("[WARN] Cliente de eventos desconectado - sin capacidad para recibir %s", event.Event)
+		}
 	}
 }
 
 ////////////////////////////////
 // *** APP_STATE ***
 
-// SaveBlockedIPs guarda la lista de IPs bloqueadas en un archivo.
+// SaveBlockedIPs guarda la lista de IPs bloqueadas en un archivo. Se escribe en un archivo
+// temporal que luego reemplaza al anterior, para no dejarlo incompleto si la escritura falla.
 func (s *service) saveBlockedIPs() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
 
-	file, err := os.Create(s.filePath)
+	data, err := json.Marshal(s.blockList.GetAll())
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	tmp := s.filePath + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filePath)
+}
 
-	data, err := json.Marshal(s.blockList.GetAll())
+// reloadState carga el estado persistido y emite un evento STATE_RELOADED. Como se carga al
+// iniciar, cuando todavia no hay suscriptores, el evento se guarda para reenviarlo a cada
+// suscriptor nuevo (ver SubscribeEvents).
+func (s *service) reloadState() {
+	count, err := s.loadBlockedIPs()
 	if err != nil {
-		return err
+		log.Printf("[ERROR] No se pudo cargar la lista de IPs bloqueadas: %v", err)
+		return
 	}
-	_, err = file.Write(data)
 
-	return err
+	event := models.Event{
+		Event:     models.EventStateReloaded,
+		Payload:   models.StateReloadedPayload{Source: s.filePath, BlockedIPs: count},
+		Timestamp: time.Now().UTC(),
+	}
+	// Se guarda y envia con s.mu para que un suscriptor nuevo no lo reciba dos veces
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloaded = &event
+	s.notifyClientsLocked(event)
 }
 
 // LoadBlockedIPs carga la lista de IPs bloqueadas desde un archivo.
-func (s *service) loadBlockedIPs() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Acepta tanto el formato actual (lista de objetos) como el anterior (lista de IPs).
+func (s *service) loadBlockedIPs() (int, error) {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
 
-	file, err := os.Open(s.filePath)
+	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// si el archivo no existe inicializa lista vacia
-			s.blockList = NewBlockList()
-			return nil
+			// si el archivo no existe la lista queda vacia
+			return 0, nil
 		}
-		return err
+		return 0, err
 	}
-	defer file.Close()
 
-	var blockedIPs []string
-	if err := json.NewDecoder(file).Decode(&blockedIPs); err != nil {
-		return err
+	var entries []BlockedIP
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var ips []string
+		if errLegacy := json.Unmarshal(data, &ips); errLegacy != nil {
+			return 0, err
+		}
+		for _, ip := range ips {
+			entries = append(entries, BlockedIP{IP: ip, BlockedAt: time.Now()})
+		}
 	}
 
-	for _, ip := range blockedIPs {
-		s.blockList.AddIP(ip)
+	for _, entry := range entries {
+		s.blockList.Restore(entry)
 	}
 
-	return nil
+	return len(entries), nil
 }
@@ -0,0 +1,120 @@
+package ipinfo
+
+import (
+	"context"
+	"errors"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"github.com/AleHts29/meli-challenge/pkg/api"
+	"testing"
+)
+
+// sourceRepository responde como currenciesRepository pero falla con api.ErrUnavailable en la
+// fuente indicada: "countries", "country" o "currency_conversion".
+type sourceRepository struct {
+	currenciesRepository
+	failing string
+}
+
+func (r *sourceRepository) fail(source string) error {
+	if r.failing == source {
+		return api.ErrUnavailable
+	}
+	return nil
+}
+
+func (r *sourceRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	if err := r.fail("countries"); err != nil {
+		return nil, err
+	}
+	return r.countingRepository.FetchCountries(ctx)
+}
+
+func (r *sourceRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	if err := r.fail("country"); err != nil {
+		return nil, err
+	}
+	return r.countingRepository.FetchCountryById(ctx, countryID)
+}
+
+func (r *sourceRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	if err := r.fail("currency_conversion"); err != nil {
+		return nil, err
+	}
+	return r.countingRepository.FetchCurrencyConversion(ctx, from, to)
+}
+
+// TestPartialLookup verifica que, si falla una API de MELI, la consulta responde la
+// geolocalizacion y lo que se pudo obtener con un aviso de la fuente, y que en modo estricto
+// falla sin reutilizar el resultado parcial de la caché.
+func TestPartialLookup(t *testing.T) {
+	tests := []struct {
+		source       string
+		wantCurrency string
+		wantRate     bool
+	}{
+		{"", "ARS", true},
+		{"countries", "ARS", true},
+		// Sin el detalle del pais no se conoce la moneda y no se cotiza
+		{"country", "", false},
+		{"currency_conversion", "ARS", false},
+	}
+	for _, tt := range tests {
+		repo := &sourceRepository{failing: tt.source}
+		s := newBenchmarkService(repo, false)
+		ctx := context.Background()
+
+		info, err := s.GetCountryDataByIP(ctx, "181.0.0.1", LookupOptions{})
+		if err != nil {
+			t.Fatalf("%q: error inesperado: %v", tt.source, err)
+		}
+		if info.IPInfo == nil || info.ID != "AR" || info.CurrencyId != tt.wantCurrency || (info.CurrencyConversionToUSD != nil) != tt.wantRate {
+			t.Errorf("%q: informacion %+v; se esperaba la moneda %q y cotizacion %t", tt.source, info, tt.wantCurrency, tt.wantRate)
+		}
+
+		if tt.source == "" {
+			if info.Partial || len(info.Warnings) != 0 {
+				t.Errorf("sin fallas se esperaba una respuesta completa, se obtuvo %+v", info.Warnings)
+			}
+			continue
+		}
+		if !info.Partial || len(info.Warnings) != 1 || info.Warnings[0].Source != tt.source || !errors.Is(info.Warnings[0].Err, api.ErrUnavailable) {
+			t.Errorf("%q: avisos %+v; se esperaba un aviso parcial de la fuente", tt.source, info.Warnings)
+		}
+
+		if _, err := s.GetCountryDataByIP(ctx, "181.0.0.1", LookupOptions{Strict: true}); !errors.Is(err, api.ErrUnavailable) {
+			t.Errorf("%q: en modo estricto se obtuvo %v; se esperaba api.ErrUnavailable", tt.source, err)
+		}
+	}
+}
+
+// TestConvertAmountFailure verifica que ConvertAmount falla si falla la cotizacion de alguna
+// moneda y que la moneda de origen no se cotiza.
+func TestConvertAmountFailure(t *testing.T) {
+	repo := &sourceRepository{failing: "currency_conversion"}
+	s := newBenchmarkService(repo, false)
+
+	if _, err := s.ConvertAmount(context.Background(), "ARS", []string{"USD"}, 100); !errors.Is(err, api.ErrUnavailable) {
+		t.Errorf("se obtuvo %v; se esperaba api.ErrUnavailable", err)
+	}
+
+	conversions, err := s.ConvertAmount(context.Background(), "ARS", []string{"ARS"}, 100)
+	if err != nil || len(conversions) != 1 || conversions[0].ConvertedAmount != 100 {
+		t.Errorf("se obtuvo %+v, %v; se esperaba el mismo monto sin cotizar", conversions, err)
+	}
+	if _, err := s.ConvertAmount(context.Background(), "ARS", []string{"XXX"}, 100); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("se obtuvo %v; se esperaba ErrUnknownCurrency", err)
+	}
+}
+
+// TestConvertAmount verifica que el monto convertido se redondea con los decimales de la moneda destino.
+func TestConvertAmount(t *testing.T) {
+	s := newBenchmarkService(&currenciesRepository{}, false)
+
+	conversions, err := s.ConvertAmount(context.Background(), "ARS", []string{"USD", "ARS"}, 1234.5678)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversions) != 2 || conversions[0].Rate != 0.001 || conversions[0].ConvertedAmount != 1.23 || conversions[0].Symbol != "U$S" || conversions[1].ConvertedAmount != 1234.57 {
+		t.Errorf("conversiones %+v; se esperaban 1.23 USD y 1234.57 ARS", conversions)
+	}
+}
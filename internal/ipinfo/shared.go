@@ -0,0 +1,87 @@
+package ipinfo
+
+import (
+	"context"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"sync"
+)
+
+// sharedRepository envuelve un Repository y comparte el resultado de cada consulta
+// a las APIs de MELI entre todas las llamadas con la misma clave. Se usa por lote,
+// por lo que los resultados viven lo que dura la resolucion del lote y todas las
+// llamadas comparten el contexto de la solicitud. Los datos vencidos que sirva la caché se
+// informan a cada llamada que usa el resultado, no solo a la que lo obtuvo.
+type sharedRepository struct {
+	Repository
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+type sharedCall struct {
+	done  chan struct{}
+	val   interface{}
+	err   error
+	stale []string // fuentes servidas vencidas al obtener val (ver markStale)
+}
+
+// newSharedRepository crea un repositorio que comparte consultas sobre r.
+func newSharedRepository(r Repository) *sharedRepository {
+	return &sharedRepository{
+		Repository: r,
+		calls:      make(map[string]*sharedCall),
+	}
+}
+
+// do ejecuta fn una unica vez por clave; las llamadas concurrentes esperan el mismo resultado.
+func (r *sharedRepository) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	r.mu.Lock()
+	call, ok := r.calls[key]
+	if ok {
+		r.mu.Unlock()
+		<-call.done
+	} else {
+		call = &sharedCall{done: make(chan struct{})}
+		r.calls[key] = call
+		r.mu.Unlock()
+
+		tracked, stale := withStaleTracking(ctx)
+		call.val, call.err = fn(tracked)
+		call.stale = stale.list()
+		close(call.done)
+	}
+
+	for _, source := range call.stale {
+		markStale(ctx, source)
+	}
+	return call.val, call.err
+}
+
+func (r *sharedRepository) FetchCountries(ctx context.Context) ([]models.Country, error) {
+	val, err := r.do(ctx, "countries", func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCountries(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]models.Country), nil
+}
+
+func (r *sharedRepository) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	val, err := r.do(ctx, "country:"+countryID, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCountryById(ctx, countryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CountryInfo), nil
+}
+
+func (r *sharedRepository) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	val, err := r.do(ctx, "currency:"+from+":"+to, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCurrencyConversion(ctx, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.CurrencyExchange), nil
+}
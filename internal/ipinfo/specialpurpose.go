@@ -0,0 +1,116 @@
+package ipinfo
+
+import (
+	"fmt"
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"net"
+)
+
+// specialPurposeRange es una entrada de los registros de direcciones de proposito especial de IANA.
+type specialPurposeRange struct {
+	network  *net.IPNet
+	category string
+	name     string
+	rfc      string
+}
+
+// specialPurposeRanges contiene los rangos de IANA que no son alcanzables globalmente
+// (ipv4-special-registry, ipv6-special-registry y los bloques multicast y reservados).
+// Algunos bloques, como 192.0.0.0/24 y 2001::/23, contienen asignaciones alcanzables
+// globalmente, que se excluyen en globallyReachableRanges. Las entradas mas especificas van primero.
+var specialPurposeRanges = mustParseRanges([]struct{ cidr, category, name, rfc string }{
+	// IPv4
+	{"0.0.0.0/8", "this-network", "This network", "RFC 791"},
+	{"10.0.0.0/8", "private-use", "Private-Use", "RFC 1918"},
+	{"100.64.0.0/10", "shared-address-space", "Shared Address Space (CGNAT)", "RFC 6598"},
+	{"127.0.0.0/8", "loopback", "Loopback", "RFC 1122"},
+	{"169.254.0.0/16", "link-local", "Link Local", "RFC 3927"},
+	{"172.16.0.0/12", "private-use", "Private-Use", "RFC 1918"},
+	{"192.0.0.0/24", "protocol-assignments", "IETF Protocol Assignments", "RFC 6890"},
+	{"192.0.2.0/24", "documentation", "Documentation (TEST-NET-1)", "RFC 5737"},
+	{"192.88.99.0/24", "deprecated", "Deprecated (6to4 Relay Anycast)", "RFC 7526"},
+	{"192.168.0.0/16", "private-use", "Private-Use", "RFC 1918"},
+	{"198.18.0.0/15", "benchmarking", "Benchmarking", "RFC 2544"},
+	{"198.51.100.0/24", "documentation", "Documentation (TEST-NET-2)", "RFC 5737"},
+	{"203.0.113.0/24", "documentation", "Documentation (TEST-NET-3)", "RFC 5737"},
+	{"224.0.0.0/4", "multicast", "Multicast", "RFC 5771"},
+	{"255.255.255.255/32", "broadcast", "Limited Broadcast", "RFC 919"},
+	{"240.0.0.0/4", "reserved", "Reserved", "RFC 1112"},
+	// IPv6
+	{"::/128", "unspecified", "Unspecified Address", "RFC 4291"},
+	{"::1/128", "loopback", "Loopback Address", "RFC 4291"},
+	{"64:ff9b:1::/48", "translation", "IPv4-IPv6 Translation (local use)", "RFC 8215"},
+	{"100::/64", "discard-only", "Discard-Only Address Block", "RFC 6666"},
+	{"2001::/32", "teredo", "TEREDO", "RFC 4380"},
+	{"2001:2::/48", "benchmarking", "Benchmarking", "RFC 5180"},
+	{"2001:10::/28", "deprecated", "Deprecated (previously ORCHID)", "RFC 4843"},
+	{"2001::/23", "protocol-assignments", "IETF Protocol Assignments", "RFC 2928"},
+	{"2001:db8::/32", "documentation", "Documentation", "RFC 3849"},
+	{"3fff::/20", "documentation", "Documentation", "RFC 9637"},
+	{"5f00::/16", "segment-routing", "Segment Routing (SRv6) SIDs", "RFC 9602"},
+	{"fc00::/7", "unique-local", "Unique-Local", "RFC 4193"},
+	{"fe80::/10", "link-local", "Link-Local Unicast", "RFC 4291"},
+	{"ff00::/8", "multicast", "Multicast", "RFC 4291"},
+})
+
+// globallyReachableRanges son las asignaciones alcanzables globalmente dentro de los bloques de
+// specialPurposeRanges, que se geolocalizan como cualquier IP publica.
+var globallyReachableRanges = mustParseRanges([]struct{ cidr, category, name, rfc string }{
+	{"192.0.0.9/32", "", "Port Control Protocol Anycast", "RFC 7723"},
+	{"192.0.0.10/32", "", "Traversal Using Relays around NAT Anycast", "RFC 8155"},
+	{"2001:1::1/128", "", "Port Control Protocol Anycast", "RFC 7723"},
+	{"2001:1::2/128", "", "Traversal Using Relays around NAT Anycast", "RFC 8155"},
+	{"2001:1::3/128", "", "DNS-SD Service Registration Protocol Anycast", "RFC 9665"},
+	{"2001:3::/32", "", "AMT", "RFC 7450"},
+	{"2001:4:112::/48", "", "AS112-v6", "RFC 7535"},
+	{"2001:20::/28", "", "ORCHIDv2", "RFC 7343"},
+	{"2001:30::/28", "", "Drone Remote ID Protocol Entity Tags (DETs) Prefix", "RFC 9374"},
+})
+
+// ClassifySpecialPurpose indica si la IP pertenece a un rango de proposito especial,
+// para el que no tiene sentido geolocalizar ni consultar las APIs externas.
+func ClassifySpecialPurpose(ip net.IP) (*models.SpecialPurposeAddress, bool) {
+	for _, r := range globallyReachableRanges {
+		if r.network.Contains(ip) {
+			return nil, false
+		}
+	}
+	for _, r := range specialPurposeRanges {
+		if r.network.Contains(ip) {
+			return &models.SpecialPurposeAddress{
+				IP:       ip.String(),
+				Network:  r.network.String(),
+				Category: r.category,
+				Name:     r.name,
+				RFC:      r.rfc,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// SpecialPurposeError se devuelve al consultar una IP de proposito especial.
+type SpecialPurposeError struct {
+	Address models.SpecialPurposeAddress
+}
+
+func (e *SpecialPurposeError) Error() string {
+	return fmt.Sprintf("la IP %s pertenece al rango de propósito especial %s (%s)", e.Address.IP, e.Address.Network, e.Address.Name)
+}
+
+// Is permite comparar el error con ErrSpecialPurposeAddress mediante errors.Is.
+func (e *SpecialPurposeError) Is(target error) bool {
+	return target == ErrSpecialPurposeAddress
+}
+
+func mustParseRanges(entries []struct{ cidr, category, name, rfc string }) []specialPurposeRange {
+	ranges := make([]specialPurposeRange, 0, len(entries))
+	for _, entry := range entries {
+		_, network, err := net.ParseCIDR(entry.cidr)
+		if err != nil {
+			panic(err)
+		}
+		ranges = append(ranges, specialPurposeRange{network: network, category: entry.category, name: entry.name, rfc: entry.rfc})
+	}
+	return ranges
+}
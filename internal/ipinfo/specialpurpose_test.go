@@ -0,0 +1,106 @@
+package ipinfo
+
+import (
+	"net"
+	"testing"
+)
+
+// TestClassifySpecialPurpose verifica una direccion de cada rango de los registros de IANA y que
+// las direcciones publicas no se clasifican.
+func TestClassifySpecialPurpose(t *testing.T) {
+	tests := []struct {
+		ip       string
+		network  string
+		category string
+	}{
+		{"0.1.2.3", "0.0.0.0/8", "this-network"},
+		{"10.20.30.40", "10.0.0.0/8", "private-use"},
+		{"100.100.1.1", "100.64.0.0/10", "shared-address-space"},
+		{"127.0.0.1", "127.0.0.0/8", "loopback"},
+		{"169.254.169.254", "169.254.0.0/16", "link-local"},
+		{"172.31.255.255", "172.16.0.0/12", "private-use"},
+		{"192.0.0.8", "192.0.0.0/24", "protocol-assignments"},
+		{"192.0.0.11", "192.0.0.0/24", "protocol-assignments"},
+		{"192.0.2.1", "192.0.2.0/24", "documentation"},
+		{"192.88.99.1", "192.88.99.0/24", "deprecated"},
+		{"192.168.1.1", "192.168.0.0/16", "private-use"},
+		{"198.19.0.1", "198.18.0.0/15", "benchmarking"},
+		{"198.51.100.7", "198.51.100.0/24", "documentation"},
+		{"203.0.113.9", "203.0.113.0/24", "documentation"},
+		{"239.255.255.250", "224.0.0.0/4", "multicast"},
+		{"255.255.255.255", "255.255.255.255/32", "broadcast"},
+		{"250.1.2.3", "240.0.0.0/4", "reserved"},
+		{"::ffff:10.0.0.1", "10.0.0.0/8", "private-use"}, // IPv4 mapeada en IPv6
+		{"::", "::/128", "unspecified"},
+		{"::1", "::1/128", "loopback"},
+		{"64:ff9b:1::a", "64:ff9b:1::/48", "translation"},
+		{"100::1", "100::/64", "discard-only"},
+		{"2001::1", "2001::/32", "teredo"},
+		{"2001:1::4", "2001::/23", "protocol-assignments"},
+		{"2001:2::1", "2001:2::/48", "benchmarking"},
+		{"2001:4:113::1", "2001::/23", "protocol-assignments"},
+		{"2001:10::1", "2001:10::/28", "deprecated"},
+		{"2001:1ff::1", "2001::/23", "protocol-assignments"},
+		{"2001:db8::1", "2001:db8::/32", "documentation"},
+		{"3fff:abc::1", "3fff::/20", "documentation"},
+		{"5f00:1::1", "5f00::/16", "segment-routing"},
+		{"fd12:3456::1", "fc00::/7", "unique-local"},
+		{"fe80::1", "fe80::/10", "link-local"},
+		{"ff02::1", "ff00::/8", "multicast"},
+	}
+	for _, tt := range tests {
+		address, ok := ClassifySpecialPurpose(net.ParseIP(tt.ip))
+		if !ok || address.Network != tt.network || address.Category != tt.category {
+			t.Errorf("%s: %+v, se esperaba %s (%s)", tt.ip, address, tt.network, tt.category)
+		}
+	}
+
+	public := []string{
+		"8.8.8.8", "190.191.1.1", "192.88.100.1", "172.32.0.1", "2001:200::1", "2800:810::1", "2a00:1450::1",
+		// asignaciones alcanzables globalmente dentro de 192.0.0.0/24 y 2001::/23
+		"192.0.0.9", "192.0.0.10", "2001:1::1", "2001:1::2", "2001:1::3", "2001:3::1", "2001:4:112::1",
+		"2001:20::1", "2001:2f:ffff::1", "2001:30::1",
+	}
+	for _, ip := range public {
+		if address, ok := ClassifySpecialPurpose(net.ParseIP(ip)); ok {
+			t.Errorf("%s: no se esperaba un rango de proposito especial, se obtuvo %+v", ip, address)
+		}
+	}
+}
+
+// TestSpecialPurposeOrder verifica que ningun rango queda oculto por otro menos especifico que
+// aparece antes en la tabla.
+func TestSpecialPurposeOrder(t *testing.T) {
+	for _, r := range specialPurposeRanges {
+		address, ok := ClassifySpecialPurpose(r.network.IP)
+		if !ok {
+			t.Errorf("%s queda excluido por globallyReachableRanges", r.network)
+			continue
+		}
+		// la primera direccion puede pertenecer a un rango mas especifico (2001::/32 en 2001::/23)
+		_, network, _ := net.ParseCIDR(address.Network)
+		if ones, _ := network.Mask.Size(); network.String() != r.network.String() && ones <= prefixLength(r.network) {
+			t.Errorf("%s queda oculto por %s", r.network, address.Network)
+		}
+	}
+}
+
+// TestGloballyReachableRanges verifica que cada excepcion esta dentro de un rango de proposito especial.
+func TestGloballyReachableRanges(t *testing.T) {
+	for _, reachable := range globallyReachableRanges {
+		contained := false
+		for _, r := range specialPurposeRanges {
+			if r.network.Contains(reachable.network.IP) && prefixLength(r.network) < prefixLength(reachable.network) {
+				contained = true
+			}
+		}
+		if !contained {
+			t.Errorf("%s no esta dentro de ningun rango de proposito especial", reachable.network)
+		}
+	}
+}
+
+func prefixLength(network *net.IPNet) int {
+	ones, _ := network.Mask.Size()
+	return ones
+}
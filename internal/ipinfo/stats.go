@@ -0,0 +1,109 @@
+package ipinfo
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"math"
+	"sort"
+	"sync"
+)
+
+const earthRadiusKm = 6371.0
+
+// usageStats acumula la cantidad de consultas por país. Es seguro para uso concurrente.
+type usageStats struct {
+	reference models.Location
+	mu        sync.Mutex
+	countries map[string]*models.CountryUsage
+}
+
+// newUsageStats crea el acumulador de estadisticas con el punto de referencia indicado.
+func newUsageStats(reference models.Location) *usageStats {
+	return &usageStats{
+		reference: reference,
+		countries: make(map[string]*models.CountryUsage),
+	}
+}
+
+// distanceTo calcula la distancia en km desde el punto de referencia, o nil si el país no tiene ubicacion.
+func (u *usageStats) distanceTo(geo *models.GeoInformation) *float64 {
+	if geo == nil {
+		return nil
+	}
+	distance := math.Round(haversineKm(u.reference, geo.Location)*100) / 100
+	return &distance
+}
+
+// record registra una consulta para el país de countryInfo.
+func (u *usageStats) record(countryInfo *models.CountryInfo) {
+	if countryInfo == nil || countryInfo.ID == "" {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	usage, ok := u.countries[countryInfo.ID]
+	if !ok {
+		usage = &models.CountryUsage{CountryID: countryInfo.ID}
+		u.countries[countryInfo.ID] = usage
+	}
+	usage.Invocations++
+	usage.CountryName = countryInfo.Name
+	if countryInfo.DistanceKm != nil {
+		usage.DistanceKm = countryInfo.DistanceKm
+	}
+}
+
+// snapshot calcula las estadisticas a partir de los contadores actuales.
+func (u *usageStats) snapshot() models.UsageStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stats := models.UsageStats{
+		Reference: u.reference,
+		Countries: make([]models.CountryUsage, 0, len(u.countries)),
+	}
+
+	var weightedSum float64
+	var weightedCount int64
+	for _, usage := range u.countries {
+		stats.TotalInvocations += usage.Invocations
+		stats.Countries = append(stats.Countries, *usage)
+
+		if usage.DistanceKm == nil {
+			continue
+		}
+		weightedSum += *usage.DistanceKm * float64(usage.Invocations)
+		weightedCount += usage.Invocations
+
+		if stats.Nearest == nil || *usage.DistanceKm < *stats.Nearest.DistanceKm {
+			nearest := *usage
+			stats.Nearest = &nearest
+		}
+		if stats.Farthest == nil || *usage.DistanceKm > *stats.Farthest.DistanceKm {
+			farthest := *usage
+			stats.Farthest = &farthest
+		}
+	}
+
+	if weightedCount > 0 {
+		average := math.Round(weightedSum/float64(weightedCount)*100) / 100
+		stats.AverageDistanceKm = &average
+	}
+
+	sort.Slice(stats.Countries, func(i, j int) bool {
+		return stats.Countries[i].Invocations > stats.Countries[j].Invocations
+	})
+	return stats
+}
+
+// haversineKm calcula la distancia sobre la superficie terrestre entre dos puntos.
+func haversineKm(from, to models.Location) float64 {
+	lat1 := from.Latitude * math.Pi / 180
+	lat2 := to.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (to.Longitude - from.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
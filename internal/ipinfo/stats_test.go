@@ -0,0 +1,92 @@
+package ipinfo
+
+import (
+	"github.com/AleHts29/meli-challenge/internal/models"
+	"math"
+	"sync"
+	"testing"
+)
+
+// TestUsageStatsConcurrent registra consultas desde varias goroutines mientras se leen las
+// estadisticas (ejecutar con -race) y verifica los totales y el promedio ponderado.
+func TestUsageStatsConcurrent(t *testing.T) {
+	stats := newUsageStats(models.Location{Latitude: -34.6, Longitude: -58.4})
+	countries := []struct {
+		info  *models.CountryInfo
+		calls int
+	}{
+		{&models.CountryInfo{Country: models.Country{ID: "AR", Name: "Argentina"}, DistanceKm: distance(10)}, 30},
+		{&models.CountryInfo{Country: models.Country{ID: "BR", Name: "Brasil"}, DistanceKm: distance(2000)}, 20},
+		{&models.CountryInfo{Country: models.Country{ID: "ES", Name: "España"}, DistanceKm: distance(10000)}, 10},
+		{&models.CountryInfo{Country: models.Country{ID: "XX", Name: "Sin ubicacion"}}, 5},
+	}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for _, c := range countries {
+				for i := 0; i < c.calls; i++ {
+					stats.record(c.info)
+				}
+			}
+			stats.record(nil) // se ignora
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				snapshot := stats.snapshot()
+				var sum int64
+				for _, usage := range snapshot.Countries {
+					sum += usage.Invocations
+				}
+				if sum != snapshot.TotalInvocations {
+					t.Errorf("total %d distinto de la suma por país %d", snapshot.TotalInvocations, sum)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := stats.snapshot()
+	if snapshot.TotalInvocations != workers*65 || len(snapshot.Countries) != 4 {
+		t.Fatalf("se esperaban %d consultas en 4 países, se obtuvo %+v", workers*65, snapshot)
+	}
+	for i, id := range []string{"AR", "BR", "ES", "XX"} {
+		if usage := snapshot.Countries[i]; usage.CountryID != id || usage.Invocations != int64(workers*countries[i].calls) {
+			t.Errorf("país %d = %+v, se esperaba %s con %d consultas", i, usage, id, workers*countries[i].calls)
+		}
+	}
+	// Los países sin ubicacion no cuentan para el promedio: (10*30 + 2000*20 + 10000*10) / 60
+	want := math.Round((10.0*30+2000*20+10000*10)/60*100) / 100
+	if snapshot.AverageDistanceKm == nil || *snapshot.AverageDistanceKm != want {
+		t.Errorf("promedio = %v, se esperaba %v", snapshot.AverageDistanceKm, want)
+	}
+	if snapshot.Nearest == nil || snapshot.Nearest.CountryID != "AR" || snapshot.Farthest == nil || snapshot.Farthest.CountryID != "ES" {
+		t.Errorf("mas cercano %+v, mas lejano %+v", snapshot.Nearest, snapshot.Farthest)
+	}
+}
+
+// TestUsageStatsEmpty verifica que sin consultas no hay promedio ni extremos.
+func TestUsageStatsEmpty(t *testing.T) {
+	snapshot := newUsageStats(models.Location{}).snapshot()
+	if snapshot.TotalInvocations != 0 || snapshot.AverageDistanceKm != nil || snapshot.Nearest != nil || snapshot.Farthest != nil || snapshot.Countries == nil {
+		t.Fatalf("estadisticas vacias inesperadas: %+v", snapshot)
+	}
+}
+
+// TestHaversine verifica la distancia entre Buenos Aires y Madrid (aproximadamente 10.040 km).
+func TestHaversine(t *testing.T) {
+	got := haversineKm(models.Location{Latitude: -34.6037, Longitude: -58.3816}, models.Location{Latitude: 40.4168, Longitude: -3.7038})
+	if math.Abs(got-10040) > 30 {
+		t.Fatalf("haversineKm = %.0f, se esperaban unos 10040 km", got)
+	}
+}
+
+// distance retorna una distancia en km como la guarda CountryInfo.
+func distance(km float64) *float64 {
+	return &km
+}
@@ -1,13 +1,68 @@
 package models
 
+import "time"
+
 type CountryInfo struct {
 	Country
-	DecimalSeparator        string      `json:"decimal_separator"`
-	ThousandsSeparator      string      `json:"thousands_separator"`
-	TimeZone                string      `json:"time_zone"`
-	GeoInformation          interface{} `json:"geo_information"` // Puede ser un struct si sabes su formato
-	CurrencyConversionToUSD interface{}
-	States                  []State `json:"states"`
+	DecimalSeparator        string               `json:"decimal_separator"`
+	ThousandsSeparator      string               `json:"thousands_separator"`
+	TimeZone                string               `json:"time_zone"`
+	GeoInformation          *GeoInformation      `json:"geo_information"`
+	CurrencyConversionToUSD *CurrencyExchange    `json:"CurrencyConversionToUSD"` // se mantiene la clave original por compatibilidad
+	Conversions             []CurrencyConversion `json:"conversions,omitempty"`   // solo presente si se pide ?to=
+	States                  []State              `json:"states"`
+	Region                  *RegionMatch         `json:"region,omitempty"`        // estado de MELI que corresponde a la region de la IP
+	DistanceKm              *float64             `json:"distance_km,omitempty"`   // distancia al punto de referencia configurado
+	Localized               *LocalizedInfo       `json:"localized,omitempty"`     // valores calculados para el país al momento de la consulta
+	IPInfo                  *IPInfo              `json:"-"`                       // resultado de IP2Location; solo se expone en /api/v2
+	Partial                 bool                 `json:"partial,omitempty"`       // true si alguna API de MELI fallo
+	Warnings                []Warning            `json:"warnings,omitempty"`      // fuentes que fallaron
+	Stale                   bool                 `json:"stale,omitempty"`         // true si algun dato de MELI se sirvio vencido de la caché
+	StaleSources            []string             `json:"stale_sources,omitempty"` // fuentes servidas vencidas
+}
+
+// GeoInformation es la ubicacion geografica de un país segun MELI.
+type GeoInformation struct {
+	Location Location `json:"location"`
+}
+
+// Location es un punto geografico en grados decimales.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// LocalizedInfo son valores calculados con la zona horaria, los separadores y la moneda del país.
+type LocalizedInfo struct {
+	LocalTime          string `json:"local_time,omitempty"`           // RFC 3339 en la zona horaria del país
+	LocalTimeFormatted string `json:"local_time_formatted,omitempty"` // dd/mm/aaaa hh:mm:ss
+	SampleAmount       string `json:"sample_amount,omitempty"`        // monto de ejemplo en la moneda del país
+	SampleAmountUSD    string `json:"sample_amount_usd,omitempty"`    // el mismo monto convertido a USD
+}
+
+// Warning describe una fuente de datos que no pudo consultarse. El handler traduce Error segun
+// la fuente, completa Code y deja el texto original del error en Debug.
+type Warning struct {
+	Source string `json:"source"`
+	Code   string `json:"code,omitempty"` // codigo de error estable, ver handler
+	Error  string `json:"error"`
+	Debug  string `json:"debug,omitempty"` // texto original del error, sin traducir
+	Err    error  `json:"-"`               // error original, para clasificarlo
+}
+
+// MarkStale registra fuentes cuyos datos se sirvieron vencidos y marca la informacion como vencida.
+func (c *CountryInfo) MarkStale(sources []string) {
+	if len(sources) == 0 {
+		return
+	}
+	c.Stale = true
+	c.StaleSources = append(c.StaleSources, sources...)
+}
+
+// AddWarning registra la falla de una fuente y marca la informacion como parcial.
+func (c *CountryInfo) AddWarning(source string, err error) {
+	c.Partial = true
+	c.Warnings = append(c.Warnings, Warning{Source: source, Error: err.Error(), Err: err})
 }
 
 type Country struct {
@@ -32,6 +87,18 @@ type CurrencyExchange struct {
 	LastUpdatedDate string  `json:"last_updated_date"`
 }
 
+// CurrencyConversion es el resultado de convertir un monto entre dos monedas.
+// ConvertedAmount se redondea a los decimales de la moneda destino.
+type CurrencyConversion struct {
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Rate            float64 `json:"rate"`
+	Amount          float64 `json:"amount"`
+	ConvertedAmount float64 `json:"converted_amount"`
+	Symbol          string  `json:"symbol"`
+	DecimalPlaces   int     `json:"decimal_places"`
+}
+
 type Currency struct {
 	ID            string `json:"id"`
 	Description   string `json:"description"`
@@ -39,13 +106,259 @@ type Currency struct {
 	DecimalPlaces int    `json:"decimal_places"`
 }
 
+// SpecialPurposeAddress describe el rango de proposito especial de IANA al que pertenece una IP.
+type SpecialPurposeAddress struct {
+	IP       string `json:"ip"`
+	Network  string `json:"network"`
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	RFC      string `json:"rfc"`
+}
+
+// CountryUsage son las estadisticas de consultas de un país.
+type CountryUsage struct {
+	CountryID   string   `json:"country_id"`
+	CountryName string   `json:"country_name"`
+	Invocations int64    `json:"invocations"`
+	DistanceKm  *float64 `json:"distance_km,omitempty"`
+}
+
+// UsageStats resume las consultas realizadas y su distancia al punto de referencia.
+type UsageStats struct {
+	Reference         Location       `json:"reference"`
+	TotalInvocations  int64          `json:"total_invocations"`
+	Nearest           *CountryUsage  `json:"nearest"`
+	Farthest          *CountryUsage  `json:"farthest"`
+	AverageDistanceKm *float64       `json:"average_distance_km"` // promedio ponderado por cantidad de consultas
+	Countries         []CountryUsage `json:"countries"`
+}
+
 type IPInfo struct {
 	IP          string `json:"ip"`
 	CountryCode string `json:"country_code"`
 	CountryName string `json:"country_name"`
+	Region      string `json:"region,omitempty"` // solo disponible con bases de IP2Location que incluyen region
+	City        string `json:"city,omitempty"`   // solo disponible con bases de IP2Location que incluyen ciudad
+}
+
+// LocationRef es la referencia a una ubicacion de MELI (país, estado o ciudad).
+type LocationRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// StateInfo es el detalle de un estado segun classified_locations/states.
+type StateInfo struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Country        LocationRef     `json:"country"`
+	GeoInformation *GeoInformation `json:"geo_information"`
+	TimeZone       string          `json:"time_zone"`
+	Cities         []LocationRef   `json:"cities"`
+}
+
+// CityInfo es el detalle de una ciudad segun classified_locations/cities.
+type CityInfo struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	State          LocationRef     `json:"state"`
+	Country        LocationRef     `json:"country"`
+	GeoInformation *GeoInformation `json:"geo_information"`
+	Neighborhoods  []LocationRef   `json:"neighborhoods"`
+}
+
+// RegionMatch asocia la region informada por IP2Location con el estado de MELI correspondiente.
+type RegionMatch struct {
+	Region    string `json:"region"`
+	City      string `json:"city,omitempty"`
+	StateID   string `json:"state_id,omitempty"`
+	StateName string `json:"state_name,omitempty"`
+}
+
+// LookupStatus clasifica el resultado de la consulta de una IP dentro de un lote.
+type LookupStatus string
+
+const (
+	LookupOK             LookupStatus = "ok"
+	LookupPartial        LookupStatus = "partial"
+	LookupBlocked        LookupStatus = "blocked"
+	LookupInvalid        LookupStatus = "invalid"
+	LookupNonMeli        LookupStatus = "non_meli"
+	LookupSpecialPurpose LookupStatus = "special_purpose"
+	LookupUpstreamFailed LookupStatus = "upstream_failed"
+)
+
+// LookupResult es el resultado de la consulta de una IP dentro de un lote.
+type LookupResult struct {
+	IP             string                 `json:"ip"`
+	Status         LookupStatus           `json:"status"`
+	Data           *CountryInfo           `json:"data,omitempty"`
+	SpecialPurpose *SpecialPurposeAddress `json:"special_purpose,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+	Code           string                 `json:"code,omitempty"` // codigo de error estable, ver handler
+	Err            error                  `json:"-"`              // error original, para clasificarlo
+}
+
+// BatchLookupResponse agrupa los resultados de un lote segun su estado.
+type BatchLookupResponse struct {
+	Count          int            `json:"count"`
+	Found          []LookupResult `json:"found"`
+	Partial        []LookupResult `json:"partial"`
+	Blocked        []string       `json:"blocked"`
+	Invalid        []string       `json:"invalid"`
+	NonMeli        []LookupResult `json:"non_meli"`
+	SpecialPurpose []LookupResult `json:"special_purpose"`
+	UpstreamFailed []LookupResult `json:"upstream_failed"`
+}
+
+// NewBatchLookupResponse crea una respuesta vacia, con listas inicializadas para serializar [] en lugar de null.
+func NewBatchLookupResponse() *BatchLookupResponse {
+	return &BatchLookupResponse{
+		Found:          []LookupResult{},
+		Partial:        []LookupResult{},
+		Blocked:        []string{},
+		Invalid:        []string{},
+		NonMeli:        []LookupResult{},
+		SpecialPurpose: []LookupResult{},
+		UpstreamFailed: []LookupResult{},
+	}
+}
+
+// Add clasifica un resultado dentro de la respuesta agrupada.
+func (b *BatchLookupResponse) Add(result LookupResult) {
+	b.Count++
+	switch result.Status {
+	case LookupOK:
+		b.Found = append(b.Found, result)
+	case LookupPartial:
+		b.Partial = append(b.Partial, result)
+	case LookupBlocked:
+		b.Blocked = append(b.Blocked, result.IP)
+	case LookupInvalid:
+		b.Invalid = append(b.Invalid, result.IP)
+	case LookupNonMeli:
+		b.NonMeli = append(b.NonMeli, result)
+	case LookupSpecialPurpose:
+		b.SpecialPurpose = append(b.SpecialPurpose, result)
+	default:
+		b.UpstreamFailed = append(b.UpstreamFailed, result)
+	}
+}
+
+// EventType identifica el tipo de un evento emitido a los suscriptores.
+type EventType string
+
+const (
+	EventBlocked          EventType = "BLOCKED"           // Una IP fue bloqueada
+	EventUnblocked        EventType = "UNBLOCKED"         // Una IP fue desbloqueada manualmente
+	EventExpired          EventType = "EXPIRED"           // El bloqueo temporal de una IP vencio
+	EventLookupDenied     EventType = "LOOKUP_DENIED"     // Se intento consultar una IP bloqueada
+	EventStateReloaded    EventType = "STATE_RELOADED"    // Se recargo el estado persistido de la aplicacion
+	EventUpstreamDegraded EventType = "UPSTREAM_DEGRADED" // Una API externa fallo al responder
+	// Ultimo evento de una suscripcion que se cerro porque el cliente no recibia los eventos a
+	// tiempo. El cliente debe reconectarse y consultar GET /api/ip/block para recuperar el estado.
+	EventSubscriptionClosed EventType = "SUBSCRIPTION_CLOSED"
+)
+
+// Event es el sobre comun de todos los eventos. Payload contiene el detalle especifico del tipo.
+type Event struct {
+	IP        string      `json:"ip,omitempty"`
+	Event     EventType   `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Payload   interface{} `json:"payload,omitempty"`
+}
+
+// BlockedPayload detalle de un evento BLOCKED.
+type BlockedPayload struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// UnblockedPayload detalle de un evento UNBLOCKED.
+type UnblockedPayload struct {
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// ExpiredPayload detalle de un evento EXPIRED.
+type ExpiredPayload struct {
+	BlockedAt time.Time `json:"blocked_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// LookupDeniedPayload detalle de un evento LOOKUP_DENIED.
+type LookupDeniedPayload struct {
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// SubscriptionClosedPayload detalle de un evento SUBSCRIPTION_CLOSED.
+type SubscriptionClosedPayload struct {
+	Reason string `json:"reason"`
+}
+
+// StateReloadedPayload detalle de un evento STATE_RELOADED.
+type StateReloadedPayload struct {
+	Source     string `json:"source"`
+	BlockedIPs int    `json:"blocked_ips"`
+}
+
+// UpstreamDegradedPayload detalle de un evento UPSTREAM_DEGRADED. Breaker es "open" cuando el
+// evento corresponde a la apertura del circuit breaker de la operacion.
+type UpstreamDegradedPayload struct {
+	Source  string       `json:"source"`
+	Error   string       `json:"error"`
+	Breaker BreakerState `json:"breaker,omitempty"`
+}
+
+// BreakerState es el estado de un circuit breaker.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Las consultas pasan normalmente
+	BreakerOpen     BreakerState = "open"      // Las consultas fallan de inmediato
+	BreakerHalfOpen BreakerState = "half_open" // Se permite una consulta de prueba
+)
+
+// BreakerStatus es el estado del circuit breaker de una operacion de MELI.
+type BreakerStatus struct {
+	Name      string       `json:"name"`
+	State     BreakerState `json:"state"`
+	Failures  int          `json:"consecutive_failures"`
+	OpenedAt  *time.Time   `json:"opened_at,omitempty"`
+	RetryAt   *time.Time   `json:"retry_at,omitempty"` // momento a partir del cual se permite la consulta de prueba
+	LastError string       `json:"last_error,omitempty"`
+}
+
+// RefreshStatus es el estado del refresco en segundo plano de un dato de referencia de MELI
+// ("countries:list", "country:AR", "rate:ARS:USD").
+type RefreshStatus struct {
+	Name                string     `json:"name"`
+	LastAttempt         time.Time  `json:"last_attempt"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"` // vencimiento del ultimo valor obtenido
+	Refreshes           int64      `json:"refreshes"`            // refrescos exitosos
+	Failures            int64      `json:"failures"`             // refrescos fallidos
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// Estados del servicio en el health check.
+const (
+	HealthOK       = "ok"       // Todas las APIs externas responden
+	HealthDegraded = "degraded" // Alguna API externa esta cortada o un refresco falla; se responde con datos en caché o parciales
+)
+
+// Health es el estado del servicio y de sus dependencias externas.
+type Health struct {
+	Status   string          `json:"status"`
+	Breakers []BreakerStatus `json:"breakers"`
+	Refresh  []RefreshStatus `json:"refresh"` // vacio si el refresco en segundo plano esta desactivado
+	Events   EventsStatus    `json:"events"`
 }
 
-type BlockEvent struct {
-	IP    string `json:"ip"`
-	Event string `json:"event"`
+// EventsStatus es el estado de las suscripciones a los eventos. Un suscriptor que no recibe los
+// eventos a tiempo se desconecta, y el evento que no pudo recibir se cuenta como descartado.
+type EventsStatus struct {
+	Subscribers  int   `json:"subscribers"`  // suscriptores conectados
+	Disconnected int64 `json:"disconnected"` // suscriptores desconectados por no recibir a tiempo
+	Dropped      int64 `json:"dropped"`      // eventos que no se entregaron a algun suscriptor
 }
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Operaciones de la API de Mercado Libre. Identifican cada endpoint en los timeouts,
+// los errores y los eventos.
+const (
+	OperationCountries          = "countries"
+	OperationCountry            = "country"
+	OperationState              = "state"
+	OperationCity               = "city"
+	OperationCurrencies         = "currencies"
+	OperationCurrencyConversion = "currency_conversion"
+	OperationToken              = "token" // endpoint OAuth de MELI
+)
+
+// Valores por defecto de ClientConfig.
+const (
+	DefaultTimeout         = 3 * time.Second
+	DefaultMaxRetries      = 2
+	DefaultBackoffBase     = 100 * time.Millisecond
+	DefaultBackoffMax      = 2 * time.Second
+	DefaultMaxIdleConns    = 100
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+// ClientConfig configura el cliente HTTP compartido. Los valores cero toman el valor por defecto,
+// salvo MaxRetries.
+type ClientConfig struct {
+	Timeout      time.Duration            // plazo de cada intento
+	Timeouts     map[string]time.Duration // plazo de cada intento por operacion, reemplaza a Timeout
+	MaxRetries   int                      // reintentos despues del primer intento (0 = sin reintentos, negativo = DefaultMaxRetries)
+	BackoffBase  time.Duration            // espera maxima antes del primer reintento; se duplica en cada uno
+	BackoffMax   time.Duration            // tope de la espera entre reintentos
+	MaxIdleConns int                      // conexiones inactivas por host que se mantienen abiertas
+	// Transport reemplaza al transporte por defecto, por ejemplo en tests.
+	Transport http.RoundTripper
+	// Mode es ModeLive (por defecto), ModeRecord o ModeReplay; los fixtures se guardan en FixturesDir.
+	Mode        string
+	FixturesDir string
+	// TokenSource provee el access token de cada consulta; nil = consultas sin autenticar.
+	TokenSource TokenSource
+}
+
+// Client realiza los GET a la API de Mercado Libre. Comparte un pool de conexiones entre todas
+// las consultas y reintenta, con backoff exponencial y jitter, los errores de red y las
+// respuestas 5xx y 429, respetando Retry-After hasta BackoffMax. Si tiene un TokenSource envia el
+// access token en cada consulta y, ante un 401, renueva el token y reintenta una vez (salvo con un
+// token fijo). Es seguro para uso concurrente.
+type Client struct {
+	httpClient  *http.Client
+	tokens      TokenSource
+	timeout     time.Duration
+	timeouts    map[string]time.Duration
+	maxRetries  int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	// sleep espera d o hasta que ctx termine; se reemplaza en tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient crea un cliente con la configuracion indicada.
+func NewClient(cfg ClientConfig) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = DefaultBackoffBase
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = DefaultBackoffMax
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = newTransport(cfg.MaxIdleConns)
+	}
+	switch cfg.Mode {
+	case ModeRecord:
+		cfg.Transport = NewRecordingTransport(cfg.FixturesDir, cfg.Transport)
+	case ModeReplay:
+		cfg.Transport = NewReplayTransport(cfg.FixturesDir)
+	}
+
+	return &Client{
+		// Sin Timeout global: el plazo de cada intento se aplica con el contexto
+		httpClient:  &http.Client{Transport: cfg.Transport},
+		tokens:      cfg.TokenSource,
+		timeout:     cfg.Timeout,
+		timeouts:    cfg.Timeouts,
+		maxRetries:  cfg.MaxRetries,
+		backoffBase: cfg.BackoffBase,
+		backoffMax:  cfg.BackoffMax,
+		sleep:       sleepContext,
+	}
+}
+
+// newTransport crea un transporte con un pool de conexiones dimensionado para un unico host. No
+// limita la espera de la respuesta: el plazo de cada intento se aplica con el contexto.
+func newTransport(maxIdleConns int) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   2 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		IdleConnTimeout:       DefaultIdleConnTimeout,
+		TLSHandshakeTimeout:   2 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// getJSON realiza un GET a endpoint y decodifica la respuesta en out, reintentando las fallas
+// transitorias mientras ctx lo permita.
+func (c *Client) getJSON(ctx context.Context, operation, endpoint string, out interface{}) error {
+	var err error
+	reauthorized := false
+	for attempt := 0; ; attempt++ {
+		var token string
+		if c.tokens != nil {
+			if token, err = c.tokens.Token(ctx); err != nil {
+				return err
+			}
+		}
+
+		var retryAfter time.Duration
+		retryAfter, err = c.attempt(ctx, operation, endpoint, token, out)
+		// Un 401 con token indica que el token vencio o fue revocado: se renueva y se reintenta
+		// una unica vez, sin contar como reintento. Un token fijo no se puede renovar.
+		if token != "" && !reauthorized && unauthorized(err) && renewable(c.tokens) && ctx.Err() == nil {
+			c.tokens.Invalidate(token)
+			reauthorized = true
+			attempt--
+			continue
+		}
+		if err == nil || attempt >= c.maxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		// Un Retry-After mayor que BackoffMax no se espera: la consulta falla con el error recibido
+		if retryAfter > c.backoffMax {
+			return err
+		}
+		wait := c.backoff(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		// No se espera si el plazo de la solicitud vence antes del proximo intento
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		if sleepErr := c.sleep(ctx, wait); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// attempt realiza un intento. Retorna el Retry-After de la respuesta, si lo hay.
+func (c *Client) attempt(ctx context.Context, operation, endpoint, token string, out interface{}) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeoutFor(operation))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, requestError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Se descarta el cuerpo para poder reutilizar la conexion
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return parseRetryAfter(resp.Header.Get("Retry-After")), &StatusError{Operation: operation, StatusCode: resp.StatusCode}
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, decodeError(err)
+	}
+	return 0, nil
+}
+
+// timeoutFor retorna el plazo de cada intento de una operacion.
+func (c *Client) timeoutFor(operation string) time.Duration {
+	if timeout, ok := c.timeouts[operation]; ok && timeout > 0 {
+		return timeout
+	}
+	return c.timeout
+}
+
+// backoff retorna la espera antes del reintento attempt+1: un valor aleatorio entre 0 y
+// BackoffBase*2^attempt, con tope BackoffMax ("full jitter").
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.backoffMax
+	if attempt < 30 {
+		if exp := c.backoffBase << attempt; exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryable indica si un error es transitorio: errores de red, timeouts, 5xx y 429.
+// Los 4xx restantes y las respuestas que no pueden interpretarse no se reintentan.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrTimeout)
+}
+
+// unauthorized indica si err es una respuesta 401 de la API.
+func unauthorized(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized
+}
+
+// parseRetryAfter interpreta Retry-After en segundos o como fecha HTTP.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// sleepContext espera d o hasta que ctx termine.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedServer responde cada consulta con el siguiente status de statuses (el ultimo se repite)
+// y cuenta las consultas. Los 200 responden {"id":"AR"}.
+func scriptedServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(statuses[n])
+		if statuses[n] == http.StatusOK {
+			w.Write([]byte(`{"id":"AR"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// fakeSleep reemplaza la espera del cliente y registra cada espera.
+func fakeSleep(c *Client) *[]time.Duration {
+	waits := &[]time.Duration{}
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+	return waits
+}
+
+// TestRetryTransient verifica que los 5xx y 429 se reintentan, con una espera de backoff entre intentos.
+func TestRetryTransient(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests} {
+		server, requests := scriptedServer(t, nil, status, status, http.StatusOK)
+		c := NewClient(ClientConfig{MaxRetries: 2, BackoffBase: 100 * time.Millisecond, BackoffMax: time.Second})
+		waits := fakeSleep(c)
+
+		var out struct{ ID string }
+		if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); err != nil {
+			t.Fatalf("%d: %v", status, err)
+		}
+		if out.ID != "AR" || requests.Load() != 3 {
+			t.Fatalf("%d: se esperaban 3 intentos y la respuesta final, se obtuvo %d intentos y %+v", status, requests.Load(), out)
+		}
+		// Full jitter: la espera de cada reintento esta entre 0 y BackoffBase*2^intento
+		if len(*waits) != 2 || (*waits)[0] > 100*time.Millisecond || (*waits)[1] > 200*time.Millisecond {
+			t.Fatalf("%d: esperas inesperadas: %v", status, *waits)
+		}
+	}
+}
+
+// TestNoRetry verifica que los 4xx distintos de 429 no se reintentan y que con MaxRetries 0 no hay reintentos.
+func TestNoRetry(t *testing.T) {
+	tests := []struct {
+		status     int
+		maxRetries int
+		want       error
+	}{
+		{http.StatusNotFound, 2, ErrNotFound},
+		{http.StatusBadRequest, 2, ErrUnavailable},
+		{http.StatusForbidden, 2, ErrUnauthorized},
+		{http.StatusServiceUnavailable, 0, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		server, requests := scriptedServer(t, nil, tt.status, http.StatusOK)
+		c := NewClient(ClientConfig{MaxRetries: tt.maxRetries})
+		waits := fakeSleep(c)
+
+		var out struct{ ID string }
+		err := c.getJSON(context.Background(), OperationCountry, server.URL, &out)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%d: se esperaba %v, se obtuvo %v", tt.status, tt.want, err)
+		}
+		if requests.Load() != 1 || len(*waits) != 0 {
+			t.Errorf("%d: se esperaba un unico intento sin esperas, se hicieron %d intentos y %v", tt.status, requests.Load(), *waits)
+		}
+	}
+}
+
+// TestRetryAfter verifica que se espera lo indicado por Retry-After y que no se reintenta si
+// supera BackoffMax.
+func TestRetryAfter(t *testing.T) {
+	server, requests := scriptedServer(t, http.Header{"Retry-After": {"1"}}, http.StatusTooManyRequests, http.StatusOK)
+	c := NewClient(ClientConfig{MaxRetries: 2, BackoffBase: time.Millisecond, BackoffMax: 2 * time.Second})
+	waits := fakeSleep(c)
+
+	var out struct{ ID string }
+	if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 2 || len(*waits) != 1 || (*waits)[0] != time.Second {
+		t.Fatalf("se esperaba un reintento tras 1s, se hicieron %d intentos con esperas %v", requests.Load(), *waits)
+	}
+
+	server, requests = scriptedServer(t, http.Header{"Retry-After": {"3600"}}, http.StatusTooManyRequests, http.StatusOK)
+	waits = fakeSleep(c)
+	if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("se esperaba ErrRateLimited, se obtuvo %v", err)
+	}
+	if requests.Load() != 1 || len(*waits) != 0 {
+		t.Fatalf("se esperaba un unico intento sin esperas, se hicieron %d intentos con esperas %v", requests.Load(), *waits)
+	}
+}
+
+// TestAttemptTimeout verifica que cada intento se corta en su plazo y se reintenta como ErrTimeout.
+func TestAttemptTimeout(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"id":"AR"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(ClientConfig{Timeout: 50 * time.Millisecond, MaxRetries: 1})
+	fakeSleep(c)
+	var out struct{ ID string }
+	if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); err != nil || out.ID != "AR" {
+		t.Fatalf("se esperaba la respuesta del reintento, se obtuvo %+v, %v", out, err)
+	}
+
+	c = NewClient(ClientConfig{Timeout: 50 * time.Millisecond})
+	requests.Store(0)
+	if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("se esperaba ErrTimeout, se obtuvo %v", err)
+	}
+}
+
+// TestBackoff verifica el tope de la espera de cada reintento.
+func TestBackoff(t *testing.T) {
+	c := NewClient(ClientConfig{BackoffBase: 100 * time.Millisecond, BackoffMax: 500 * time.Millisecond})
+	ceilings := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond}
+	for attempt, ceiling := range ceilings {
+		for i := 0; i < 100; i++ {
+			if wait := c.backoff(attempt); wait < 0 || wait > ceiling {
+				t.Fatalf("backoff(%d) = %v, se esperaba entre 0 y %v", attempt, wait, ceiling)
+			}
+		}
+	}
+	if wait := c.backoff(64); wait > 500*time.Millisecond {
+		t.Fatalf("backoff(64) = %v supera BackoffMax", wait)
+	}
+}
@@ -1,79 +1,68 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"github.com/AleHts29/meli-challenge/internal/models"
-	"net/http"
+	"net/url"
 )
 
 type Countries interface {
-	FetchCountries() ([]models.Country, error)
-	FetchCountryById(countryID string) (*models.CountryInfo, error)
+	FetchCountries(ctx context.Context) ([]models.Country, error)
+	FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error)
+	FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error)
+	FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error)
 }
 
 type apiCountries struct {
-	//accessToken string
 	apiUrl string
+	client *Client
 }
 
-// NewCountries crea una nueva instancia de la estructura Contries.
-func NewCountries(accessToken, apiUrl string) Countries {
+// NewCountries crea una nueva instancia de la estructura Contries. Si client es nil se usa
+// un cliente con la configuracion por defecto.
+func NewCountries(apiUrl string, client *Client) Countries {
+	if client == nil {
+		client = NewClient(ClientConfig{})
+	}
 	return &apiCountries{
-		//accessToken: accessToken,
 		apiUrl: apiUrl,
+		client: client,
 	}
 }
 
 // FetchCountries consulta la API de Mercado Libre para obtener información sobre países.
-func (a *apiCountries) FetchCountries() ([]models.Country, error) {
-	url := fmt.Sprintf("%s/classified_locations/countries", a.apiUrl)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-	//req.Header.Set("Authorization", "Bearer "+a.accessToken)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error fetching countries: status code %d", resp.StatusCode)
-	}
+func (a *apiCountries) FetchCountries(ctx context.Context) ([]models.Country, error) {
 	var countries []models.Country
-	if err = json.NewDecoder(resp.Body).Decode(&countries); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+	if err := a.client.getJSON(ctx, OperationCountries, fmt.Sprintf("%s/classified_locations/countries", a.apiUrl), &countries); err != nil {
+		return nil, err
 	}
-
 	return countries, nil
 }
 
 // FetchCountryById consulta la API de Mercado Libre para obtener información sobre un país específico.
-func (a *apiCountries) FetchCountryById(countryID string) (*models.CountryInfo, error) {
-	url := fmt.Sprintf("%s/classified_locations/countries/%s", a.apiUrl, countryID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+func (a *apiCountries) FetchCountryById(ctx context.Context, countryID string) (*models.CountryInfo, error) {
+	var country models.CountryInfo
+	if err := a.client.getJSON(ctx, OperationCountry, fmt.Sprintf("%s/classified_locations/countries/%s", a.apiUrl, url.PathEscape(countryID)), &country); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &country, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error fetching countryByID: status code %d", resp.StatusCode)
+// FetchStateById consulta la API de Mercado Libre para obtener un estado con sus ciudades.
+func (a *apiCountries) FetchStateById(ctx context.Context, stateID string) (*models.StateInfo, error) {
+	var state models.StateInfo
+	if err := a.client.getJSON(ctx, OperationState, fmt.Sprintf("%s/classified_locations/states/%s", a.apiUrl, url.PathEscape(stateID)), &state); err != nil {
+		return nil, err
 	}
+	return &state, nil
+}
 
-	var country models.CountryInfo
-	if err = json.NewDecoder(resp.Body).Decode(&country); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+// FetchCityById consulta la API de Mercado Libre para obtener una ciudad con sus barrios.
+func (a *apiCountries) FetchCityById(ctx context.Context, cityID string) (*models.CityInfo, error) {
+	var city models.CityInfo
+	if err := a.client.getJSON(ctx, OperationCity, fmt.Sprintf("%s/classified_locations/cities/%s", a.apiUrl, url.PathEscape(cityID)), &city); err != nil {
+		return nil, err
 	}
-	return &country, nil
+	return &city, nil
 }
@@ -1,79 +1,51 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"github.com/AleHts29/meli-challenge/internal/models"
-	"net/http"
+	"net/url"
 )
 
 type Currencies interface {
-	FetchCurrencies() ([]models.Currency, error)
-	FetchCurrenciesConversionToUSD(currencyId string) (*models.CurrencyExchange, error)
+	FetchCurrencies(ctx context.Context) ([]models.Currency, error)
+	FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error)
 }
 
 type apiCurrencies struct {
-	//accessToken string
 	apiUrl string
+	client *Client
 }
 
-// NewCountries crea una nueva instancia de la estructura Contries.
-func NewCurrencies(accessToken, apiUrl string) Currencies {
+// NewCurrencies crea una nueva instancia de la estructura Currencies. Si client es nil se usa
+// un cliente con la configuracion por defecto.
+func NewCurrencies(apiUrl string, client *Client) Currencies {
+	if client == nil {
+		client = NewClient(ClientConfig{})
+	}
 	return &apiCurrencies{
-		//accessToken: accessToken,
 		apiUrl: apiUrl,
+		client: client,
 	}
 }
 
-// FetchCountries consulta la API de Mercado Libre para obtener información sobre países.
-func (a *apiCurrencies) FetchCurrencies() ([]models.Currency, error) {
-	url := fmt.Sprintf("%s/currencies", a.apiUrl)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-	//req.Header.Set("Authorization", "Bearer "+a.accessToken)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error fetching countries: status code %d", resp.StatusCode)
-	}
+// FetchCurrencies consulta la API de Mercado Libre para obtener las monedas.
+func (a *apiCurrencies) FetchCurrencies(ctx context.Context) ([]models.Currency, error) {
 	var currencies []models.Currency
-	if err = json.NewDecoder(resp.Body).Decode(&currencies); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+	if err := a.client.getJSON(ctx, OperationCurrencies, fmt.Sprintf("%s/currencies", a.apiUrl), &currencies); err != nil {
+		return nil, err
 	}
-
 	return currencies, nil
 }
 
-// CountryById consulta la API de Mercado Libre para obtener información sobre un país específico.
-func (a *apiCurrencies) FetchCurrenciesConversionToUSD(currencyId string) (*models.CurrencyExchange, error) {
-	url := fmt.Sprintf("%s/currency_conversions/search?from=%s&to=USD", a.apiUrl, currencyId)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
+// FetchCurrencyConversion consulta la API de Mercado Libre para obtener la cotización entre dos monedas.
+func (a *apiCurrencies) FetchCurrencyConversion(ctx context.Context, from, to string) (*models.CurrencyExchange, error) {
+	query := url.Values{"from": {from}, "to": {to}}
+	endpoint := fmt.Sprintf("%s/currency_conversions/search?%s", a.apiUrl, query.Encode())
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error fetching currencies conversion: status code %d", resp.StatusCode)
-	}
 	var currency models.CurrencyExchange
-	if err = json.NewDecoder(resp.Body).Decode(&currency); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+	if err := a.client.getJSON(ctx, OperationCurrencyConversion, endpoint, &currency); err != nil {
+		return nil, err
 	}
 	return &currency, nil
 }
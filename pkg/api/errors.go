@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+var (
+	// ErrUnavailable indica que la API de Mercado Libre no respondio o respondio con un error 5xx.
+	ErrUnavailable = errors.New("upstream unavailable")
+	// ErrTimeout indica que la API de Mercado Libre no respondio a tiempo.
+	ErrTimeout = errors.New("upstream timeout")
+	// ErrNotFound indica que el recurso consultado no existe en la API de Mercado Libre.
+	ErrNotFound = errors.New("upstream resource not found")
+	// ErrRateLimited indica que la API de Mercado Libre rechazo la solicitud por exceso de consultas.
+	ErrRateLimited = errors.New("upstream rate limited")
+	// ErrUnauthorized indica que la API de Mercado Libre rechazo las credenciales (401 o 403), o que
+	// no pudo obtenerse un access token con ellas.
+	ErrUnauthorized = errors.New("upstream unauthorized")
+	// ErrBadResponse indica que la respuesta de la API de Mercado Libre no pudo interpretarse.
+	ErrBadResponse = errors.New("upstream bad response")
+)
+
+// StatusError es la respuesta de la API de Mercado Libre con un status distinto de 200.
+type StatusError struct {
+	Operation  string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("error fetching %s: status code %d", e.Operation, e.StatusCode)
+}
+
+// Unwrap permite comparar un StatusError con los errores sentinela mediante errors.Is.
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.Operation == OperationToken && e.StatusCode == http.StatusBadRequest:
+		// El endpoint de tokens responde 400 (invalid_grant, invalid_client) ante credenciales invalidas
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusGatewayTimeout:
+		return ErrTimeout
+	default:
+		return ErrUnavailable
+	}
+}
+
+// requestError clasifica el error de transporte de una solicitud.
+func requestError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("error making request: %w: %w", ErrTimeout, err)
+	}
+	return fmt.Errorf("error making request: %w: %w", ErrUnavailable, err)
+}
+
+// decodeError clasifica el error al interpretar una respuesta.
+func decodeError(err error) error {
+	return fmt.Errorf("error decoding response: %w: %w", ErrBadResponse, err)
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Modos de acceso a la API de Mercado Libre.
+const (
+	ModeLive   = "live"   // consultas a la API real
+	ModeRecord = "record" // consultas a la API real, guardando cada respuesta como fixture
+	ModeReplay = "replay" // respuestas servidas desde los fixtures, sin acceso a la red
+)
+
+// fixture es una respuesta grabada de la API de Mercado Libre. Body guarda las respuestas JSON
+// tal cual, para que puedan editarse a mano; Text, las que no son JSON.
+type fixture struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"` // path y query, sin el host
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+}
+
+// recordingTransport realiza las consultas con next y guarda cada respuesta en dir.
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// NewRecordingTransport crea un transporte que consulta con next y guarda como fixture en dir,
+// reemplazando el anterior de la misma consulta, las respuestas 2xx y 404. Las demas (5xx, 429,
+// 401) son fallas transitorias o de credenciales y no se graban, para que un reintento o una
+// caida de la API no pise un fixture valido.
+func NewRecordingTransport(dir string, next http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{dir: dir, next: next}
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if !recordable(resp.StatusCode) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	record := fixture{
+		Method:      req.Method,
+		URL:         req.URL.RequestURI(),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if json.Valid(body) {
+		record.Body = body
+	} else {
+		record.Text = string(body)
+	}
+	// Una falla al grabar no afecta a la consulta
+	if err := writeFixture(filepath.Join(t.dir, fixtureName(req)), record); err != nil {
+		log.Printf("[WARN] No se pudo grabar el fixture de %s: %v", req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+// recordable indica si una respuesta con status se graba: las exitosas y los 404, que son la
+// respuesta estable de la API para un recurso inexistente.
+func recordable(status int) bool {
+	return status >= 200 && status < 300 || status == http.StatusNotFound
+}
+
+// writeFixture escribe un fixture de forma atomica.
+func writeFixture(path string, record fixture) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// replayTransport responde cada consulta con el fixture grabado en dir.
+type replayTransport struct {
+	dir string
+}
+
+// NewReplayTransport crea un transporte que responde con los fixtures de dir, sin acceso a la red.
+// Las consultas sin fixture fallan como si la API no estuviera disponible.
+func NewReplayTransport(dir string) http.RoundTripper {
+	return &replayTransport{dir: dir}
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	data, err := os.ReadFile(filepath.Join(t.dir, fixtureName(req)))
+	if err != nil {
+		return nil, fmt.Errorf("no fixture for %s %s: %w", req.Method, req.URL.RequestURI(), err)
+	}
+	var record fixture
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid fixture for %s %s: %w", req.Method, req.URL.RequestURI(), err)
+	}
+
+	body := []byte(record.Text)
+	if len(record.Body) > 0 {
+		body = record.Body
+	}
+	header := make(http.Header)
+	if record.ContentType != "" {
+		header.Set("Content-Type", record.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", record.Status, http.StatusText(record.Status)),
+		StatusCode:    record.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// fixtureName es el nombre del archivo de una consulta: el path con "_" en lugar de "/" y la
+// query, ordenada, despues de "@" ("currency_conversions_search@from=ARS&to=USD.json"). Los
+// metodos distintos de GET se agregan como prefijo.
+func fixtureName(req *http.Request) string {
+	name := strings.ReplaceAll(strings.Trim(req.URL.Path, "/"), "/", "_")
+	if query := req.URL.Query(); len(query) > 0 {
+		name += "@" + query.Encode()
+	}
+	if req.Method != http.MethodGet {
+		name = strings.ToLower(req.Method) + "_" + name
+	}
+	return name + ".json"
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// TestRecordReplay verifica que las respuestas grabadas en modo record se sirven igual en modo replay.
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/classified_locations/countries/AR":
+			w.Write([]byte(`{"id":"AR","name":"Argentina","currency_id":"ARS"}`))
+		case "/currency_conversions/search":
+			w.Write([]byte(`{"currency_base":"` + r.URL.Query().Get("from") + `","currency_quote":"USD","rate":0.001}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	recording := NewClient(ClientConfig{Mode: ModeRecord, FixturesDir: dir})
+	if _, err := NewCountries(server.URL, recording).FetchCountryById(ctx, "AR"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCurrencies(server.URL, recording).FetchCurrencyConversion(ctx, "ARS", "USD"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCountries(server.URL, recording).FetchCountryById(ctx, "XX"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("se esperaba ErrNotFound, se obtuvo %v", err)
+	}
+	server.Close()
+
+	replaying := NewClient(ClientConfig{Mode: ModeReplay, FixturesDir: dir})
+	country, err := NewCountries(server.URL, replaying).FetchCountryById(ctx, "AR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if country.Name != "Argentina" || country.CurrencyId != "ARS" {
+		t.Errorf("país inesperado: %+v", country)
+	}
+	rate, err := NewCurrencies(server.URL, replaying).FetchCurrencyConversion(ctx, "ARS", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.CurrencyBase != "ARS" || rate.Rate != 0.001 {
+		t.Errorf("cotización inesperada: %+v", rate)
+	}
+	// Los 404 tambien se graban
+	if _, err = NewCountries(server.URL, replaying).FetchCountryById(ctx, "XX"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("se esperaba ErrNotFound, se obtuvo %v", err)
+	}
+	// Sin fixture la API no esta disponible
+	if _, err = NewCountries(server.URL, replaying).FetchCountryById(ctx, "BR"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("se esperaba ErrUnavailable, se obtuvo %v", err)
+	}
+}
+
+// TestRecordSkipsTransientErrors verifica que las respuestas 5xx y 429 no reemplazan un fixture grabado.
+func TestRecordSkipsTransientErrors(t *testing.T) {
+	var status atomic.Int64
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte(`{"id":"AR","name":"Argentina"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	recording := NewClient(ClientConfig{Mode: ModeRecord, FixturesDir: dir})
+	if _, err := NewCountries(server.URL, recording).FetchCountryById(ctx, "AR"); err != nil {
+		t.Fatal(err)
+	}
+	for _, failure := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusUnauthorized} {
+		status.Store(int64(failure))
+		if _, err := NewCountries(server.URL, recording).FetchCountryById(ctx, "AR"); err == nil {
+			t.Fatalf("%d: se esperaba un error", failure)
+		}
+	}
+	// Una consulta que nunca respondio 2xx ni 404 no deja fixture
+	if _, err := NewCountries(server.URL, recording).FetchCountryById(ctx, "BR"); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "classified_locations_countries_BR.json")); !os.IsNotExist(err) {
+		t.Fatalf("no se esperaba un fixture para BR: %v", err)
+	}
+
+	replaying := NewClient(ClientConfig{Mode: ModeReplay, FixturesDir: dir})
+	country, err := NewCountries(server.URL, replaying).FetchCountryById(ctx, "AR")
+	if err != nil || country.Name != "Argentina" {
+		t.Fatalf("se esperaba el fixture original, se obtuvo %+v, %v", country, err)
+	}
+}
+
+// TestShippedFixtures verifica que fixtures/meli resuelve cada pais de la lista: su detalle, su
+// moneda y la cotizacion de su moneda a USD.
+func TestShippedFixtures(t *testing.T) {
+	const host = "http://fixtures.invalid"
+	client := NewClient(ClientConfig{Mode: ModeReplay, FixturesDir: filepath.Join("..", "..", "fixtures", "meli")})
+	countries, currencies := NewCountries(host, client), NewCurrencies(host, client)
+	ctx := context.Background()
+
+	list, err := countries.FetchCountries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("la lista de paises esta vacia")
+	}
+	currencyList, err := currencies.FetchCurrencies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := map[string]bool{}
+	for _, currency := range currencyList {
+		known[currency.ID] = true
+	}
+
+	for _, summary := range list {
+		country, err := countries.FetchCountryById(ctx, summary.ID)
+		if err != nil {
+			t.Errorf("%s: %v", summary.ID, err)
+			continue
+		}
+		if country.ID != summary.ID || country.CurrencyId != summary.CurrencyId || country.Locale != summary.Locale {
+			t.Errorf("%s: el detalle %+v no coincide con la lista %+v", summary.ID, country.Country, summary)
+		}
+		if country.GeoInformation == nil {
+			t.Errorf("%s: sin geo_information", summary.ID)
+		}
+		if !known[summary.CurrencyId] {
+			t.Errorf("%s: la moneda %s no esta en /currencies", summary.ID, summary.CurrencyId)
+		}
+		rate, err := currencies.FetchCurrencyConversion(ctx, summary.CurrencyId, "USD")
+		if err != nil {
+			t.Errorf("%s: cotizacion de %s: %v", summary.ID, summary.CurrencyId, err)
+			continue
+		}
+		if rate.CurrencyBase != summary.CurrencyId || rate.CurrencyQuote != "USD" || rate.Rate <= 0 {
+			t.Errorf("%s: cotizacion inesperada %+v", summary.ID, rate)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Valores por defecto de OAuthConfig.
+const (
+	DefaultTokenURL      = "https://api.mercadolibre.com/oauth/token"
+	DefaultRefreshBefore = time.Minute
+)
+
+// Espera entre renovaciones fallidas del token: se duplica en cada falla, hasta el tope.
+const (
+	tokenRetryBase = time.Second
+	tokenRetryMax  = time.Minute
+)
+
+// TokenSource provee el access token que se envia en el header Authorization de cada consulta.
+type TokenSource interface {
+	// Token retorna un token vigente, obteniendolo o renovandolo si hace falta.
+	Token(ctx context.Context) (string, error)
+	// Invalidate descarta token si sigue siendo el actual, por ejemplo porque MELI respondio 401.
+	// La proxima llamada a Token obtiene uno nuevo.
+	Invalidate(token string)
+}
+
+// staticToken es un token fijo que no se renueva.
+type staticToken string
+
+// StaticToken crea un TokenSource que siempre retorna token.
+func StaticToken(token string) TokenSource {
+	return staticToken(token)
+}
+
+func (t staticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t staticToken) Invalidate(string) {}
+
+// renewable indica si s puede obtener un token distinto despues de Invalidate. Un token fijo no
+// se renueva, por lo que no tiene sentido reintentar una consulta rechazada con 401.
+func renewable(s TokenSource) bool {
+	_, static := s.(staticToken)
+	return !static
+}
+
+// OAuthConfig configura el flujo OAuth de MELI. Si RefreshToken esta vacio se usa client_credentials.
+type OAuthConfig struct {
+	TokenURL      string        // endpoint de tokens (por defecto DefaultTokenURL)
+	ClientID      string        // APP ID de la aplicacion de MELI
+	ClientSecret  string        // secret key de la aplicacion
+	RefreshToken  string        // refresh token inicial para el flujo refresh_token
+	RefreshBefore time.Duration // margen antes del vencimiento en el que se renueva el token
+	// RefreshTokenFile guarda el refresh token vigente. MELI entrega uno nuevo en cada renovacion
+	// e invalida el anterior, por lo que sin este archivo RefreshToken queda usado al reiniciar.
+	// Si el archivo existe tiene prioridad sobre RefreshToken.
+	RefreshTokenFile string
+	// HTTPClient realiza las solicitudes al endpoint de tokens; por defecto uno con DefaultTimeout.
+	HTTPClient *http.Client
+}
+
+// oauthTokenSource obtiene y renueva access tokens de MELI. Hay a lo sumo una renovacion en curso:
+// mientras el token actual no vence, la renovacion anticipada se hace en segundo plano y las
+// consultas siguen usandolo; sin token vigente, las consultas esperan la renovacion. Despues de
+// una falla no se reintenta hasta retryAt.
+type oauthTokenSource struct {
+	cfg OAuthConfig
+	now func() time.Time
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string // MELI entrega un refresh token nuevo en cada renovacion
+	expiresAt    time.Time
+	refreshAt    time.Time
+	refreshing   chan struct{} // se cierra al terminar la renovacion en curso; nil si no hay
+	failures     int           // renovaciones fallidas consecutivas
+	retryAt      time.Time     // no se intenta renovar antes de este momento
+	lastErr      error         // error de la ultima renovacion fallida
+}
+
+// tokenResponse es la respuesta del endpoint de tokens.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// NewOAuthTokenSource crea un TokenSource con el flujo OAuth de MELI.
+func NewOAuthTokenSource(cfg OAuthConfig) TokenSource {
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = DefaultTokenURL
+	}
+	if cfg.RefreshBefore <= 0 {
+		cfg.RefreshBefore = DefaultRefreshBefore
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	refreshToken := cfg.RefreshToken
+	if cfg.RefreshTokenFile != "" {
+		if saved, err := os.ReadFile(cfg.RefreshTokenFile); err == nil && len(bytes.TrimSpace(saved)) > 0 {
+			refreshToken = string(bytes.TrimSpace(saved))
+		}
+	}
+	return &oauthTokenSource{
+		cfg:          cfg,
+		now:          time.Now,
+		refreshToken: refreshToken,
+	}
+}
+
+// Token retorna el token actual. Si esta por vencer inicia su renovacion en segundo plano y lo
+// sigue retornando; si no hay token vigente espera la renovacion o el fin de ctx.
+func (s *oauthTokenSource) Token(ctx context.Context) (string, error) {
+	for {
+		s.mu.Lock()
+		now := s.now()
+		valid := s.accessToken != "" && now.Before(s.expiresAt)
+		if valid && now.Before(s.refreshAt) {
+			token := s.accessToken
+			s.mu.Unlock()
+			return token, nil
+		}
+
+		if s.refreshing == nil {
+			if now.Before(s.retryAt) {
+				// La ultima renovacion fallo: se usa el token mientras no venza, sin reintentar
+				token, err := s.accessToken, s.lastErr
+				s.mu.Unlock()
+				if valid {
+					return token, nil
+				}
+				return "", err
+			}
+			s.refreshing = make(chan struct{})
+			go s.refresh(s.refreshToken, s.refreshing)
+		}
+		if valid {
+			token := s.accessToken
+			s.mu.Unlock()
+			return token, nil
+		}
+		done := s.refreshing
+		s.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// refresh obtiene un token nuevo y cierra done al terminar. No depende del contexto de ninguna
+// consulta: el plazo es el de HTTPClient.
+func (s *oauthTokenSource) refresh(refreshToken string, done chan struct{}) {
+	defer close(done)
+	token, err := s.fetch(context.Background(), refreshToken)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = nil
+	now := s.now()
+	if err != nil {
+		s.failures++
+		wait := tokenRetryMax
+		if s.failures < 8 {
+			if exp := tokenRetryBase << (s.failures - 1); exp < wait {
+				wait = exp
+			}
+		}
+		s.retryAt, s.lastErr = now.Add(wait), err
+		log.Printf("[WARN] No se pudo renovar el access token de MELI (%d fallas consecutivas, proximo intento en %s): %v", s.failures, wait, err)
+		return
+	}
+
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	margin := s.cfg.RefreshBefore
+	if margin > lifetime/2 {
+		margin = lifetime / 2
+	}
+	s.accessToken = token.AccessToken
+	s.expiresAt = now.Add(lifetime)
+	s.refreshAt = s.expiresAt.Add(-margin)
+	s.failures, s.retryAt, s.lastErr = 0, time.Time{}, nil
+	if token.RefreshToken != "" && token.RefreshToken != s.refreshToken {
+		s.refreshToken = token.RefreshToken
+		s.saveRefreshToken()
+	}
+}
+
+// saveRefreshToken guarda el refresh token en RefreshTokenFile, si se configuro, de forma atomica.
+func (s *oauthTokenSource) saveRefreshToken() {
+	path := s.cfg.RefreshTokenFile
+	if path == "" {
+		return
+	}
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, []byte(s.refreshToken+"\n"), 0o600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		log.Printf("[WARN] No se pudo guardar el refresh token de MELI en %s: %v", path, err)
+	}
+}
+
+// Invalidate descarta token si sigue siendo el actual.
+func (s *oauthTokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token == s.accessToken {
+		s.accessToken = ""
+	}
+}
+
+// fetch solicita un token nuevo con refreshToken, si no esta vacio, o con client_credentials.
+func (s *oauthTokenSource) fetch(ctx context.Context, refreshToken string) (*tokenResponse, error) {
+	form := url.Values{
+		"client_id":     {s.cfg.ClientID},
+		"client_secret": {s.cfg.ClientSecret},
+	}
+	if refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating token request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching access token: %w", requestError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("error fetching access token: %w", &StatusError{Operation: OperationToken, StatusCode: resp.StatusCode})
+	}
+
+	var token tokenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("error fetching access token: %w", decodeError(err))
+	}
+	if token.AccessToken == "" || token.ExpiresIn <= 0 {
+		return nil, fmt.Errorf("error fetching access token: %w: missing access_token or expires_in", ErrBadResponse)
+	}
+	return &token, nil
+}
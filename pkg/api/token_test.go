@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer es un endpoint de tokens que emite "access-N" y "refresh-N" con una hora de vigencia
+// y registra los refresh tokens recibidos. Mientras failing sea true responde 500.
+type tokenServer struct {
+	*httptest.Server
+	issued  atomic.Int64
+	failing atomic.Bool
+
+	mu       sync.Mutex
+	received []string // refresh_token de cada solicitud ("" con client_credentials)
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		ts.mu.Lock()
+		ts.received = append(ts.received, r.PostForm.Get("refresh_token"))
+		ts.mu.Unlock()
+		if ts.failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		n := ts.issued.Add(1)
+		json.NewEncoder(w).Encode(tokenResponse{
+			AccessToken:  fmt.Sprintf("access-%d", n),
+			ExpiresIn:    3600,
+			RefreshToken: fmt.Sprintf("refresh-%d", n),
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) requests() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]string(nil), ts.received...)
+}
+
+// fakeClock es un reloj que solo avanza con advance.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{}
+	c.now.Store(time.Now().UnixNano())
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+// newTestTokenSource crea un oauthTokenSource con el reloj clock.
+func newTestTokenSource(cfg OAuthConfig, clock *fakeClock) *oauthTokenSource {
+	s := NewOAuthTokenSource(cfg).(*oauthTokenSource)
+	s.now = clock.Now
+	return s
+}
+
+// waitRefreshed espera a que termine la renovacion en segundo plano.
+func waitRefreshed(t *testing.T, s *oauthTokenSource) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		refreshing := s.refreshing != nil
+		s.mu.Unlock()
+		if !refreshing {
+			return
+		}
+	}
+	t.Fatal("la renovacion del token no termino")
+}
+
+// TestTokenRefreshBeforeExpiry verifica que dentro del margen de renovacion se sigue usando el
+// token vigente mientras se renueva en segundo plano con el refresh token rotado.
+func TestTokenRefreshBeforeExpiry(t *testing.T) {
+	ts := newTokenServer(t)
+	clock := newFakeClock()
+	file := filepath.Join(t.TempDir(), "refresh_token")
+	s := newTestTokenSource(OAuthConfig{TokenURL: ts.URL, RefreshToken: "initial", RefreshBefore: time.Minute, RefreshTokenFile: file}, clock)
+	ctx := context.Background()
+
+	if token, err := s.Token(ctx); err != nil || token != "access-1" {
+		t.Fatalf("Token() = %q, %v", token, err)
+	}
+	clock.advance(59 * time.Minute)
+	if token, _ := s.Token(ctx); token != "access-1" {
+		t.Fatalf("dentro del margen se esperaba el token vigente, se obtuvo %q", token)
+	}
+	waitRefreshed(t, s)
+	if token, _ := s.Token(ctx); token != "access-2" {
+		t.Fatalf("se esperaba el token renovado, se obtuvo %q", token)
+	}
+
+	if got := ts.requests(); len(got) != 2 || got[0] != "initial" || got[1] != "refresh-1" {
+		t.Fatalf("refresh tokens enviados inesperados: %v", got)
+	}
+	// El refresh token rotado se guarda y se usa al reiniciar
+	if saved, _ := os.ReadFile(file); string(saved) != "refresh-2\n" {
+		t.Fatalf("refresh token guardado inesperado: %q", saved)
+	}
+	if restarted := NewOAuthTokenSource(OAuthConfig{RefreshToken: "initial", RefreshTokenFile: file}).(*oauthTokenSource); restarted.refreshToken != "refresh-2" {
+		t.Fatalf("al reiniciar se esperaba el refresh token guardado, se obtuvo %q", restarted.refreshToken)
+	}
+}
+
+// TestTokenRefreshFailure verifica que si la renovacion falla se sigue usando el token mientras no
+// venza, que no se reintenta antes de la espera y que sin token vigente se retorna el error.
+func TestTokenRefreshFailure(t *testing.T) {
+	ts := newTokenServer(t)
+	clock := newFakeClock()
+	s := newTestTokenSource(OAuthConfig{TokenURL: ts.URL, RefreshBefore: time.Minute}, clock)
+	ctx := context.Background()
+
+	if _, err := s.Token(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ts.failing.Store(true)
+	clock.advance(59*time.Minute + 30*time.Second)
+	for i := 0; i < 5; i++ {
+		if token, err := s.Token(ctx); err != nil || token != "access-1" {
+			t.Fatalf("se esperaba el token vigente, se obtuvo %q, %v", token, err)
+		}
+		waitRefreshed(t, s)
+	}
+	if n := len(ts.requests()); n != 2 {
+		t.Fatalf("se esperaba un unico intento de renovacion durante la espera, se hicieron %d", n-1)
+	}
+
+	// Vencido el token, la consulta espera la renovacion y retorna su error
+	clock.advance(time.Minute)
+	if _, err := s.Token(ctx); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("se esperaba ErrUnavailable, se obtuvo %v", err)
+	}
+
+	// Al recuperarse el endpoint se obtiene un token nuevo una vez pasada la espera
+	ts.failing.Store(false)
+	clock.advance(tokenRetryMax)
+	if token, err := s.Token(ctx); err != nil || token != "access-2" {
+		t.Fatalf("se esperaba un token nuevo, se obtuvo %q, %v", token, err)
+	}
+}
+
+// TestRetryOn401 verifica que ante un 401 el cliente renueva el token y reintenta una unica vez.
+func TestRetryOn401(t *testing.T) {
+	ts := newTokenServer(t)
+	var calls atomic.Int64
+	var rejectAll atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if rejectAll.Load() || r.Header.Get("Authorization") != "Bearer access-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id":"AR"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(ClientConfig{TokenSource: NewOAuthTokenSource(OAuthConfig{TokenURL: ts.URL})})
+	var out struct{ ID string }
+	if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); err != nil || out.ID != "AR" {
+		t.Fatalf("se esperaba la respuesta con el token renovado, se obtuvo %+v, %v", out, err)
+	}
+	if calls.Load() != 2 || ts.issued.Load() != 2 {
+		t.Fatalf("se esperaban 2 consultas y 2 tokens, se hicieron %d y %d", calls.Load(), ts.issued.Load())
+	}
+
+	// Si el token nuevo tambien es rechazado no se vuelve a reintentar
+	calls.Store(0)
+	rejectAll.Store(true)
+	if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("se esperaba ErrUnauthorized, se obtuvo %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("se esperaba un unico reintento, se hicieron %d consultas", calls.Load())
+	}
+}
+
+// TestStaticToken401 verifica que un 401 con un token fijo no se reintenta, porque el token no se
+// puede renovar, y se informa como ErrUnauthorized.
+func TestStaticToken401(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "Bearer ACCESS_TOKEN" {
+			t.Errorf("Authorization inesperado: %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c := NewClient(ClientConfig{TokenSource: StaticToken("ACCESS_TOKEN"), MaxRetries: 2})
+	var out struct{ ID string }
+	if err := c.getJSON(context.Background(), OperationCountry, server.URL, &out); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("se esperaba ErrUnauthorized, se obtuvo %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("se esperaba una unica consulta, se hicieron %d", calls.Load())
+	}
+}
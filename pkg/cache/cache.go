@@ -1,28 +1,65 @@
 package cache
 
 import (
+	"context"
 	"sync"
 	"time"
 )
 
+// Item es un elemento del cache. Hasta ExpiresAt (TTL blando) esta vigente; entre ExpiresAt y
+// StaleUntil (TTL duro) esta vencido pero puede servirse mientras se refresca; despues de
+// StaleUntil no se sirve.
 type Item struct {
-	Data      interface{}
-	ExpiresAt time.Time
+	Data       interface{}
+	ExpiresAt  time.Time
+	StaleUntil time.Time
+}
+
+// Fresh indica si el elemento esta vigente en now.
+func (i Item) Fresh(now time.Time) bool {
+	return !now.After(i.ExpiresAt)
+}
+
+// Usable indica si el elemento puede servirse en now, vigente o vencido.
+func (i Item) Usable(now time.Time) bool {
+	return !now.After(i.StaleUntil)
 }
 
 type Cache struct {
-	store map[string]Item
-	mu    sync.RWMutex
-	ttl   time.Duration
+	store             map[string]Item
+	mu                sync.RWMutex
+	ttl               time.Duration
+	stale             time.Duration       // margen despues del vencimiento en el que un elemento se sirve vencido
+	refreshing        map[string]struct{} // claves con un refresco en curso (revalidacion de Fetch o Refresh)
+	revalidateTimeout time.Duration       // plazo de cada revalidacion en segundo plano (0 = sin plazo)
 }
 
 func NewCache(ttl time.Duration) *Cache {
+	return NewStaleCache(ttl, 0)
+}
+
+// NewStaleCache crea un cache cuyos elementos, despues de vencer, se pueden seguir sirviendo
+// durante stale mientras se refrescan (ver Fetch).
+func NewStaleCache(ttl, stale time.Duration) *Cache {
+	if stale < 0 {
+		stale = 0
+	}
 	return &Cache{
-		store: make(map[string]Item),
-		ttl:   ttl,
+		store:      make(map[string]Item),
+		ttl:        ttl,
+		stale:      stale,
+		refreshing: make(map[string]struct{}),
 	}
 }
 
+// SetRevalidateTimeout limita la duracion de cada revalidacion en segundo plano de Fetch, que no
+// depende del plazo de la solicitud que la disparo. Con timeout 0 no hay plazo.
+func (c *Cache) SetRevalidateTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revalidateTimeout = timeout
+}
+
 // Get obtiene un elemento del cache.
 func (c *Cache) Get(key string) (interface{}, bool) {
 	c.mu.RLock()
@@ -36,14 +73,119 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	return item.Data, true
 }
 
+// Peek obtiene un elemento del cache aunque haya vencido o superado su margen de vencido.
+func (c *Cache) Peek(key string) (Item, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	item, exists := c.store[key]
+	return item, exists
+}
+
+// Fetch obtiene un elemento del cache, usando load para obtenerlo cuando no esta vigente:
+//   - Vigente: se retorna sin consultar load.
+//   - Vencido dentro del margen (stale-while-revalidate): se retorna de inmediato, marcado como
+//     vencido, y se refresca con load en segundo plano. Hay a lo sumo un refresco por clave en
+//     curso; si falla se sigue sirviendo el valor vencido (stale-if-error) hasta el proximo intento.
+//   - Ausente o fuera del margen: se obtiene con load y se retorna su error si falla.
+//
+// load retorna el valor y su tiempo de vida. En segundo plano recibe un contexto sin la
+// cancelacion de ctx, porque la solicitud que lo disparo ya fue respondida, con el plazo de
+// SetRevalidateTimeout.
+func (c *Cache) Fetch(ctx context.Context, key string, load func(ctx context.Context) (interface{}, time.Duration, error)) (data interface{}, stale bool, err error) {
+	now := time.Now()
+	c.mu.Lock()
+	item, exists := c.store[key]
+	if exists && item.Fresh(now) {
+		c.mu.Unlock()
+		return item.Data, false, nil
+	}
+	if exists && item.Usable(now) {
+		if c.claimLocked(key) {
+			go c.revalidate(context.WithoutCancel(ctx), c.revalidateTimeout, key, load)
+		}
+		c.mu.Unlock()
+		return item.Data, true, nil
+	}
+	c.mu.Unlock()
+
+	val, ttl, err := load(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	c.SetWithTTL(key, val, ttl)
+	return val, false, nil
+}
+
+// revalidate refresca key en segundo plano, con plazo timeout si es mayor a 0. Si load falla
+// se conserva el valor vencido.
+func (c *Cache) revalidate(ctx context.Context, timeout time.Duration, key string, load func(ctx context.Context) (interface{}, time.Duration, error)) {
+	defer c.release(key)
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if val, ttl, err := load(ctx); err == nil {
+		c.SetWithTTL(key, val, ttl)
+	}
+}
+
+// Refresh obtiene key con load y la guarda, este o no vigente. Si ya hay un refresco de key en
+// curso (una revalidacion de Fetch u otro Refresh) no consulta load y retorna false. Si load falla
+// se conserva el valor anterior y se retorna su error.
+func (c *Cache) Refresh(ctx context.Context, key string, load func(ctx context.Context) (interface{}, time.Duration, error)) (bool, error) {
+	c.mu.Lock()
+	claimed := c.claimLocked(key)
+	c.mu.Unlock()
+	if !claimed {
+		return false, nil
+	}
+	defer c.release(key)
+
+	val, ttl, err := load(ctx)
+	if err != nil {
+		return true, err
+	}
+	c.SetWithTTL(key, val, ttl)
+	return true, nil
+}
+
+// claimLocked registra un refresco de key en curso. Retorna false si ya habia uno. Requiere c.mu.
+func (c *Cache) claimLocked(key string) bool {
+	if _, running := c.refreshing[key]; running {
+		return false
+	}
+	c.refreshing[key] = struct{}{}
+	return true
+}
+
+// release registra el fin del refresco de key.
+func (c *Cache) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.refreshing, key)
+}
+
 // Set agrega o actualiza un elemento en el cache.
 func (c *Cache) Set(key string, data interface{}) {
+	c.SetWithTTL(key, data, c.ttl)
+}
+
+// SetWithTTL agrega o actualiza un elemento en el cache con un tiempo de expiracion propio. El
+// elemento se puede servir vencido durante el margen del cache.
+func (c *Cache) SetWithTTL(key string, data interface{}, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	expiresAt := time.Now().Add(ttl)
 	c.store[key] = Item{
-		Data:      data,
-		ExpiresAt: time.Now().Add(c.ttl),
+		Data:       data,
+		ExpiresAt:  expiresAt,
+		StaleUntil: expiresAt.Add(c.stale),
 	}
 }
 
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitRevalidated espera a que terminen los refrescos en segundo plano.
+func waitRevalidated(t *testing.T, c *Cache) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mu.RLock()
+		pending := len(c.refreshing)
+		c.mu.RUnlock()
+		if pending == 0 {
+			return
+		}
+	}
+	t.Fatal("el refresco en segundo plano no termino")
+}
+
+// TestFetchStaleWhileRevalidate verifica que un elemento vencido dentro del margen se sirve de
+// inmediato mientras un unico refresco en segundo plano obtiene el valor nuevo.
+func TestFetchStaleWhileRevalidate(t *testing.T) {
+	c := NewStaleCache(time.Minute, time.Hour)
+	c.SetWithTTL("k", "old", -time.Second)
+
+	var calls atomic.Int64
+	release := make(chan struct{})
+	load := func(ctx context.Context) (interface{}, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return "new", time.Minute, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, stale, err := c.Fetch(context.Background(), "k", load)
+			if err != nil || data != "old" || !stale {
+				t.Errorf("Fetch = %v, %v, %v; se esperaba el valor vencido", data, stale, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+	waitRevalidated(t, c)
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("se esperaba 1 refresco, se hicieron %d", n)
+	}
+	if data, stale, err := c.Fetch(context.Background(), "k", load); err != nil || data != "new" || stale {
+		t.Fatalf("Fetch = %v, %v, %v; se esperaba el valor refrescado", data, stale, err)
+	}
+}
+
+// TestFetchStaleIfError verifica que si el refresco falla se sigue sirviendo el valor vencido
+// dentro del margen, y que fuera del margen se retorna el error.
+func TestFetchStaleIfError(t *testing.T) {
+	errUpstream := errors.New("upstream down")
+	failing := func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, errUpstream
+	}
+
+	c := NewStaleCache(time.Minute, time.Hour)
+	c.SetWithTTL("k", "old", -time.Second)
+	for i := 0; i < 2; i++ {
+		data, stale, err := c.Fetch(context.Background(), "k", failing)
+		if err != nil || data != "old" || !stale {
+			t.Fatalf("Fetch = %v, %v, %v; se esperaba el valor vencido", data, stale, err)
+		}
+		waitRevalidated(t, c)
+	}
+
+	// Sin margen el elemento vencido no se sirve
+	c = NewCache(time.Minute)
+	c.SetWithTTL("k", "old", -time.Second)
+	if _, _, err := c.Fetch(context.Background(), "k", failing); !errors.Is(err, errUpstream) {
+		t.Fatalf("se esperaba el error del refresco, se obtuvo %v", err)
+	}
+}
+
+// TestRefresh verifica que Refresh reemplaza un elemento vigente, que conserva el anterior si
+// load falla y que no corre mientras la clave se revalida.
+func TestRefresh(t *testing.T) {
+	c := NewStaleCache(time.Minute, time.Hour)
+	c.SetWithTTL("k", "old", time.Minute)
+	ctx := context.Background()
+
+	refreshed, err := c.Refresh(ctx, "k", func(ctx context.Context) (interface{}, time.Duration, error) {
+		return "new", time.Minute, nil
+	})
+	if data, _ := c.Get("k"); !refreshed || err != nil || data != "new" {
+		t.Fatalf("Refresh = %v, %v; valor %v", refreshed, err, data)
+	}
+
+	errUpstream := errors.New("upstream down")
+	refreshed, err = c.Refresh(ctx, "k", func(ctx context.Context) (interface{}, time.Duration, error) {
+		return nil, 0, errUpstream
+	})
+	if data, _ := c.Get("k"); !refreshed || !errors.Is(err, errUpstream) || data != "new" {
+		t.Fatalf("Refresh = %v, %v; valor %v", refreshed, err, data)
+	}
+
+	// Con una revalidacion en curso no se consulta load
+	c.SetWithTTL("k", "old", -time.Second)
+	release := make(chan struct{})
+	c.Fetch(ctx, "k", func(ctx context.Context) (interface{}, time.Duration, error) {
+		<-release
+		return "revalidated", time.Minute, nil
+	})
+	refreshed, err = c.Refresh(ctx, "k", func(ctx context.Context) (interface{}, time.Duration, error) {
+		t.Error("no se esperaba consultar load durante la revalidacion")
+		return nil, 0, nil
+	})
+	if refreshed || err != nil {
+		t.Fatalf("Refresh = %v, %v; se esperaba omitirlo", refreshed, err)
+	}
+	close(release)
+	waitRevalidated(t, c)
+	if data, _ := c.Get("k"); data != "revalidated" {
+		t.Fatalf("se esperaba el valor revalidado, se obtuvo %v", data)
+	}
+}
+
+// TestFetchRevalidateTimeout verifica que la revalidacion en segundo plano termina al vencer su
+// plazo aunque la solicitud que la disparo no tenga plazo, y que se sigue sirviendo el valor vencido.
+func TestFetchRevalidateTimeout(t *testing.T) {
+	c := NewStaleCache(time.Minute, time.Hour)
+	c.SetRevalidateTimeout(20 * time.Millisecond)
+	c.SetWithTTL("k", "old", -time.Second)
+
+	done := make(chan error, 1)
+	wedged := func(ctx context.Context) (interface{}, time.Duration, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("la revalidacion no tiene plazo")
+		}
+		<-ctx.Done()
+		done <- ctx.Err()
+		return nil, 0, ctx.Err()
+	}
+	if data, stale, err := c.Fetch(context.Background(), "k", wedged); err != nil || data != "old" || !stale {
+		t.Fatalf("Fetch = %v, %v, %v; se esperaba el valor vencido", data, stale, err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("la revalidacion termino con %v; se esperaba context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("la revalidacion no termino al vencer su plazo")
+	}
+	waitRevalidated(t, c)
+	if data, stale, err := c.Fetch(context.Background(), "k", wedged); err != nil || data != "old" || !stale {
+		t.Fatalf("Fetch = %v, %v, %v; se esperaba el valor vencido", data, stale, err)
+	}
+	<-done
+	waitRevalidated(t, c)
+}
@@ -1,9 +1,12 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/AleHts29/meli-challenge/internal/models"
 	"github.com/ip2location/ip2location-go/v9"
+	"strings"
 )
 
 //	db, err := ip2location.OpenDB("./IP-COUNTRY-REGION-CITY-LATITUDE-LONGITUDE-ZIPCODE-TIMEZONE-ISP-DOMAIN-NETSPEED-AREACODE-WEATHER-MOBILE-ELEVATION-USAGETYPE-ADDRESSTYPE-CATEGORY-DISTRICT-ASN-DB26.BIN")
@@ -26,8 +29,15 @@ import (
 //	fmt.Printf("city: %s\n", results.City)
 //	fmt.Printf("isp: %s\n", results.Isp)
 
+var (
+	// ErrIPNotFound indica que la IP no tiene un país asociado en la base de IP2Location.
+	ErrIPNotFound = errors.New("ip not found in ip2location database")
+	// ErrLookupFailed indica que la base de IP2Location no pudo resolver la consulta.
+	ErrLookupFailed = errors.New("ip2location lookup failed")
+)
+
 type IpStore interface {
-	GetCountryByIP(ip string) (*models.IPInfo, error)
+	GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error)
 }
 
 type ipStore struct {
@@ -42,15 +52,37 @@ func NewIpStore(dbPath string) (IpStore, error) {
 	return &ipStore{db: db}, nil
 }
 
-func (i *ipStore) GetCountryByIP(ip string) (*models.IPInfo, error) {
+// GetCountryByIP geolocaliza una IP. La base es local y la consulta no puede interrumpirse,
+// por lo que ctx solo se verifica antes de consultarla.
+func (i *ipStore) GetCountryByIP(ctx context.Context, ip string) (*models.IPInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	results, err := i.db.Get_all(ip)
 	if err != nil {
-		fmt.Print(err)
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrLookupFailed, err)
 	}
+
+	// IP2Location devuelve "-" cuando la IP no pertenece a ningun rango conocido, y un
+	// mensaje en lugar del codigo cuando la base no soporta la IP (ej: IPv6 en una base IPv4)
+	if len(results.Country_short) != 2 {
+		return nil, fmt.Errorf("%w: %s", ErrIPNotFound, ip)
+	}
+
 	return &models.IPInfo{
 		IP:          ip,
 		CountryCode: results.Country_short,
 		CountryName: results.Country_long,
+		Region:      supportedField(results.Region),
+		City:        supportedField(results.City),
 	}, nil
 }
+
+// supportedField descarta los valores que IP2Location devuelve cuando la base no incluye el campo.
+func supportedField(value string) string {
+	if value == "-" || strings.HasPrefix(value, "This parameter is unavailable") {
+		return ""
+	}
+	return value
+}